@@ -0,0 +1,664 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angrynarwhal/networks/graph"
+)
+
+// configEnv names the environment variable that sets the config path when
+// -config isn't given.
+const configEnv = "NETWORKS_CONFIG"
+
+// defaultConfigPath returns the config path to use without a -config flag:
+// $NETWORKS_CONFIG if set, otherwise config.json in the working directory.
+func defaultConfigPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+	return "config.json"
+}
+
+// runVerify implements the "verify" subcommand: it loads a config and a saved
+// network and reports which consistency checks pass. It returns false if any fail.
+func runVerify(args []string) bool {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "config the network was generated from; $"+configEnv+" overrides the default")
+	networkPath := fs.String("in", "network.json", "network file to check")
+	fs.Parse(args)
+
+	config, err := graph.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		return false
+	}
+	G, err := graph.ReadNetwork(*networkPath)
+	if err != nil {
+		fmt.Println("Error reading network:", err)
+		return false
+	}
+	passed := true
+	for _, check := range graph.VerifyNetwork(config, G) {
+		status := "PASS"
+		if !check.OK {
+			status = "FAIL"
+			passed = false
+		}
+		fmt.Printf("%s  %-13s %s\n", status, check.Name, check.Detail)
+	}
+	if passed {
+		fmt.Printf("%s is consistent with %s\n", *networkPath, *configPath)
+	} else {
+		fmt.Printf("%s does not match %s\n", *networkPath, *configPath)
+	}
+	return passed
+}
+
+// runDiff implements the "diff" subcommand: it reports the edges added and
+// removed and the weights changed between two saved networks, such as two
+// snapshots of one run. It returns false if either file can't be read.
+func runDiff(args []string) bool {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	list := fs.Bool("list", false, "also list every added and removed edge and every weight change")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: simulate diff [-list] BEFORE AFTER")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return false
+	}
+	before, err := graph.ReadNetwork(fs.Arg(0))
+	if err != nil {
+		fmt.Println("Error reading network:", err)
+		return false
+	}
+	after, err := graph.ReadNetwork(fs.Arg(1))
+	if err != nil {
+		fmt.Println("Error reading network:", err)
+		return false
+	}
+	if before.NumAgents != after.NumAgents {
+		fmt.Printf("Warning: %s has %d nodes, %s has %d\n", fs.Arg(0), before.NumAgents, fs.Arg(1), after.NumAgents)
+	}
+	added, removed, weightChanges := graph.Diff(before, after)
+	fmt.Printf("%s between %s and %s\n", graph.DiffSummary(added, removed, weightChanges), fs.Arg(0), fs.Arg(1))
+	if *list {
+		for _, edge := range added {
+			fmt.Printf("+ %s -> %s (weight %d)\n", after.Label(edge.Source), after.Label(edge.Target), edge.Weight)
+		}
+		for _, edge := range removed {
+			fmt.Printf("- %s -> %s (weight %d)\n", before.Label(edge.Source), before.Label(edge.Target), edge.Weight)
+		}
+		keys := make([]graph.EdgeKey, 0, len(weightChanges))
+		for key := range weightChanges {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].Source != keys[j].Source {
+				return keys[i].Source < keys[j].Source
+			}
+			return keys[i].Target < keys[j].Target
+		})
+		for _, key := range keys {
+			fmt.Printf("~ %s -> %s (weight %+d)\n", after.Label(key.Source), after.Label(key.Target), weightChanges[key])
+		}
+	}
+	return true
+}
+
+// Command-line flags. Settings that describe the network itself live in the config file.
+var (
+	configPath        = flag.String("config", defaultConfigPath(), "config file to read; $"+configEnv+" overrides the default")
+	epidemicModel     = flag.String("epidemic", "", "after generating, run an epidemic (sir or sis) and write epidemic.csv")
+	beta              = flag.Float64("beta", 0.1, "epidemic infection probability per edge per step")
+	gamma             = flag.Float64("gamma", 0.05, "epidemic recovery probability per step")
+	initialInfected   = flag.Int("initial-infected", 1, "number of randomly chosen initially infected nodes")
+	epidemicSteps     = flag.Int("epidemic-steps", 100, "maximum number of epidemic steps")
+	cascadeSeeds      = flag.String("cascade", "", "after generating, run a linear threshold cascade seeded by 'random' or 'degree' and write cascade.csv")
+	numSeeds          = flag.Int("cascade-seeds", 5, "number of initial adopters for the cascade")
+	threshold         = flag.Float64("threshold", 0, "adoption threshold for every node (0 draws a random threshold per node)")
+	influenceSeeds    = flag.Int("influence-seeds", 0, "greedily select this many influence-maximizing seed nodes")
+	influenceModel    = flag.String("influence-model", "ic", "diffusion model for seed selection: ic or lt")
+	influenceProb     = flag.Float64("influence-prob", 0.1, "activation probability per edge for the ic model")
+	influenceTrials   = flag.Int("influence-trials", 100, "Monte Carlo runs per spread estimate")
+	comparePath       = flag.String("compare", "", "print the edge-set Jaccard similarity between the result and this network file")
+	reportPath        = flag.String("report", "", "write a Markdown summary of the run to this file (e.g. report.md)")
+	damping           = flag.Float64("damping", 0.85, "PageRank damping factor: the probability of following an edge rather than jumping to a random node")
+	pprSeeds          = flag.String("ppr-seeds", "", "comma-separated seed nodes; print the top nodes by personalized PageRank from them")
+	betweenness       = flag.Bool("betweenness", false, "compute exact betweenness centrality, print the top nodes and write centrality.json")
+	closeness         = flag.Bool("closeness", false, "compute closeness centrality, print the top nodes and write centrality.json")
+	eigenvector       = flag.Bool("eigenvector", false, "compute eigenvector centrality, print the top nodes and write centrality.json")
+	approxBetweenness = flag.Int("approx-betweenness", 0, "estimate betweenness from this many sampled source nodes and print the top nodes")
+	countOnly         = flag.Bool("count-only", false, "only count edges and print summary metrics as one JSON line; no files are written")
+	statsOnly         = flag.Bool("stats", false, "simulate and print summary metrics without writing any files (same as stats_only in the config)")
+	progressMode      = flag.String("progress", "auto", "step progress display: bar, lines, none, or auto (a bar on a terminal, one line per step otherwise)")
+	inputPath         = flag.String("input", "", "load a network (.json, .graphml or .gml) instead of simulating one")
+	pathSamples       = flag.Int("path-samples", 0, "estimate average path length and diameter from this many sampled source nodes instead of all of them")
+	scaling           = flag.String("scaling", "", "MIN,MAX: generate networks of geometrically spaced sizes and write scaling.csv")
+	scalingPoints     = flag.Int("scaling-points", 5, "number of sizes between MIN and MAX for -scaling")
+	rewireSwaps       = flag.Int("rewire", 0, "randomize the network with this many double-edge swaps before the analyses, as a degree-preserving null model")
+	timing            = flag.Bool("timing", false, "print the wall-clock time spent generating the network, analyzing it and writing output files")
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		if !runVerify(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if !runDiff(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	flag.Parse()
+
+	config, err := graph.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(1)
+	}
+	if err := run(config); err != nil {
+		fmt.Println("Error", err)
+		os.Exit(1)
+	}
+}
+
+// run generates (or loads) the network config describes, runs the analyses
+// selected by the flags and writes the output files. Errors are returned for
+// main to report rather than exiting here.
+func run(config *graph.Config) error {
+	// Check flags before generating anything, so a bad value can't fail the
+	// run halfway with some output files already written.
+	if *damping <= 0 || *damping >= 1 {
+		return fmt.Errorf("in -damping: must be in (0, 1), got %g", *damping)
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		// Count-only output must stay a single JSON line, so the seed goes to stderr there.
+		out := os.Stdout
+		if *countOnly {
+			out = os.Stderr
+		}
+		fmt.Fprintf(out, "Random seed: %d (set \"seed\" in %s to reproduce this run)\n", seed, *configPath)
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	if *scaling != "" {
+		bounds := strings.Split(*scaling, ",")
+		var smallest, largest int
+		var err error
+		if len(bounds) == 2 {
+			smallest, err = strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err == nil {
+				largest, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			}
+		}
+		if len(bounds) != 2 || err != nil || smallest < 2 || largest < smallest {
+			return fmt.Errorf("in -scaling: expected MIN,MAX with 2 <= MIN <= MAX, got '%s'", *scaling)
+		}
+		rows, err := graph.RunScaling(config, graph.GeometricSizes(smallest, largest, *scalingPoints), rng)
+		for _, row := range rows {
+			fmt.Printf("n=%d: %d edges, average degree %.3f, %.2fs\n", row.Stats.NumAgents, row.Stats.Edges, row.Stats.AverageDegree, row.Seconds)
+		}
+		if writeErr := graph.WriteScalingCSV(rows, "scaling.csv"); writeErr != nil {
+			return fmt.Errorf("writing scaling.csv: %w", writeErr)
+		}
+		if err != nil {
+			return fmt.Errorf("during scaling run: %w", err)
+		}
+		fmt.Println("Scaling results saved to scaling.csv")
+		return nil
+	}
+
+	start, err := graph.StartNetwork(config)
+	if err != nil {
+		return fmt.Errorf("loading seed_network: %w", err)
+	}
+
+	if *countOnly {
+		if len(config.Pipeline) > 0 {
+			return fmt.Errorf("in -count-only: pipelines are not supported")
+		}
+		opts := graph.NewSimOptions(config)
+		opts.CountOnly = true
+		opts.Start = start
+		network, err := graph.Simulate(config, opts, rng)
+		if err != nil {
+			return fmt.Errorf("during simulation: %w", err)
+		}
+		line, _ := json.Marshal(network.CountStats())
+		fmt.Println(string(line))
+		return nil
+	}
+
+	fmt.Printf("Running simulation with the following parameters:\n")
+	fmt.Printf("Agents: %d, Time Steps: %d, Dynamic: %t, Directed: %t, Edge Weights: %t\n",
+		config.NumAgents, config.TimeSteps, config.Dynamic, config.Directed, config.EdgeWeights)
+	if len(config.Pipeline) > 0 {
+		fmt.Printf("Pipeline: %d stages\n", len(config.Pipeline))
+	} else {
+		fmt.Printf("Linking Strategy: %s\n", config.LinkingStrategy)
+	}
+
+	labels, err := graph.NodeLabels(config)
+	if err != nil {
+		return fmt.Errorf("loading node labels: %w", err)
+	}
+
+	opts := graph.NewSimOptions(config)
+	if start != nil {
+		opts.Start = start
+		fmt.Printf("Continuing from %s (%d edges)\n", config.SeedNetwork, len(start.Edges))
+	}
+	if *statsOnly {
+		config.StatsOnly = true
+	}
+	// networkFile names a saved network, compressed when the config asks.
+	networkFile := func(name string) string {
+		if config.Compress {
+			return name + ".gz"
+		}
+		return name
+	}
+	progress, bar, err := progressFunc(*progressMode)
+	if err != nil {
+		return err
+	}
+	opts.ProgressFunc = progress
+	if config.SnapshotInterval > 0 && !config.StatsOnly {
+		opts.StepFunc = func(step int, g *graph.Graph) {
+			if step%config.SnapshotInterval != 0 {
+				return
+			}
+			// SaveNetwork copies every edge into the file as it is now, so later
+			// steps can't alter a snapshot.
+			path := networkFile(fmt.Sprintf("network_t%d.json", step))
+			bar.Break()
+			if err := graph.SaveNetwork(g, path); err != nil {
+				fmt.Printf("Error writing snapshot %s: %v\n", path, err)
+				return
+			}
+			fmt.Printf("Snapshot saved to %s\n", path)
+		}
+	}
+	opts.DecayFunc = func(step, removed int) {
+		bar.Break()
+		fmt.Printf("Step %d: weight decay removed %d edges\n", step, removed)
+	}
+	opts.ChurnFunc = func(step, removed int) {
+		bar.Break()
+		fmt.Printf("Step %d: churn removed %d edges\n", step, removed)
+	}
+	opts.StageFunc = func(stage int, strategy string) {
+		bar.Break()
+		fmt.Printf("Pipeline stage %d: %s\n", stage, strategy)
+	}
+	opts.RewireFunc = func(stage, rewired int) {
+		bar.Break()
+		fmt.Printf("Homophily Rewire - %d edges rewired toward same-group nodes\n", rewired)
+	}
+	if config.StopOnConvergence {
+		opts.StopWhen = graph.ConvergenceCheck(config.ConvergenceMetric, config.ConvergenceTolerance, config.ConvergencePatience, opts)
+		opts.ConvergedFunc = func(step int, value float64) {
+			bar.Break()
+			fmt.Printf("Converged at time step %d: %s changed by less than %g for %d steps (value %.4f)\n",
+				step, config.ConvergenceMetric, config.ConvergenceTolerance, config.ConvergencePatience, value)
+		}
+	}
+
+	generationStart := time.Now()
+	var network *graph.Graph
+	if *inputPath != "" {
+		network, err = graph.ReadNetwork(*inputPath)
+		if err != nil {
+			return fmt.Errorf("reading input network: %w", err)
+		}
+		fmt.Printf("Loaded network from %s instead of simulating\n", *inputPath)
+	} else {
+		if len(config.Pipeline) > 0 {
+			network, err = graph.RunPipeline(config, opts, rng)
+		} else {
+			network, err = graph.Simulate(config, opts, rng)
+		}
+		// A run stopped early (convergence, memory limit) leaves the bar unfinished.
+		bar.Break()
+		if network == nil {
+			return fmt.Errorf("during simulation: %w", err)
+		}
+		if labels != nil {
+			network.Labels = labels
+		}
+	}
+	generationTime := time.Since(generationStart)
+	if err != nil && config.StatsOnly {
+		return fmt.Errorf("during simulation: %w", err)
+	}
+	if err != nil {
+		// Write whatever was generated before the abort so the run isn't a total loss.
+		path := networkFile("network.json")
+		if saveErr := graph.SaveNetwork(network, path); saveErr != nil {
+			fmt.Printf("Error writing partial %s: %v\n", path, saveErr)
+		} else {
+			fmt.Printf("Partial network saved to %s\n", path)
+		}
+		return fmt.Errorf("during simulation: %w", err)
+	}
+
+	fmt.Printf("Simulation complete. Network has %d nodes and %d edges.\n", network.NumAgents, len(network.Edges))
+	if network.Removed != nil {
+		fmt.Printf("Live nodes: %d of %d (%d removed by the death process)\n", network.LiveNodes(), network.NumAgents, len(network.Removed))
+	}
+	if network.CapHits() > 0 {
+		fmt.Printf("Multiplicity cap of %d reached: %d repeated links ignored\n", config.MaxMultiplicity, network.CapHits())
+	}
+	if *rewireSwaps > 0 {
+		swapped := graph.Rewire(network, *rewireSwaps, rng)
+		fmt.Printf("Rewired into a degree-preserving null model: %d of %d swaps succeeded\n", swapped, *rewireSwaps)
+	}
+	metrics := graph.ComputeMetrics(network)
+	stats := graph.ComputeStats(network)
+	fmt.Printf("Density: %.4f, average degree: %.3f, max degree: %d, isolated nodes: %d\n",
+		stats.Density, stats.AverageDegree, stats.MaxDegree, stats.IsolatedNodes)
+	fmt.Printf("Degree entropy: %.4f bits\n", metrics.DegreeEntropy)
+	fmt.Printf("Clustering coefficient: %.4f\n", metrics.ClusteringCoefficient)
+	fmt.Printf("Triangles: %d\n", graph.CountTriangles(network))
+	if network.NumEdges() == 0 {
+		fmt.Println("Warning: degree assortativity is undefined for a network without edges")
+	}
+	if network.Directed {
+		fmt.Printf("Degree assortativity: %.4f undirected, %.4f directed (out-degree to in-degree)\n",
+			graph.DegreeAssortativity(network, false), graph.DegreeAssortativity(network, true))
+		fmt.Printf("Reciprocity: %.4f of edges have a reverse edge\n", graph.Reciprocity(network))
+	} else {
+		fmt.Printf("Degree assortativity: %.4f\n", graph.DegreeAssortativity(network, false))
+	}
+	fmt.Printf("Connected components: %d (largest has %d nodes)\n", metrics.Components, metrics.LargestComponent)
+	fmt.Printf("Max core: k = %d (%d nodes)\n", metrics.MaxCore, metrics.MaxCoreSize)
+	paths := graph.ShortestPaths(network, *pathSamples, rng)
+	fmt.Printf("Largest component: average path length %.3f, diameter %d", paths.AverageLength, paths.Diameter)
+	if paths.Sources < metrics.LargestComponent {
+		fmt.Printf(" (estimated from %d sampled sources)", paths.Sources)
+	}
+	fmt.Println()
+	if paths.Unreachable > 0 {
+		fmt.Printf("  %d of %d ordered node pairs have no directed path and are left out\n", paths.Unreachable, paths.Reachable+paths.Unreachable)
+	}
+	if len(network.Groups) > 0 {
+		fmt.Printf("Modularity of the groups: %.4f\n", graph.Modularity(network, network.Groups))
+		averages := network.DegreeByGroup(network.Groups)
+		sizes := make(map[int]int)
+		for _, group := range network.Groups {
+			sizes[group]++
+		}
+		groupIDs := make([]int, 0, len(averages))
+		for group := range averages {
+			groupIDs = append(groupIDs, group)
+		}
+		sort.Ints(groupIDs)
+		fmt.Println("Group  Nodes  Avg Degree")
+		for _, group := range groupIDs {
+			fmt.Printf("%5d  %5d  %10.2f\n", group, sizes[group], averages[group])
+		}
+	}
+	if config.StatsOnly {
+		fmt.Println("Stats only: no files written")
+		printTiming(generationTime, time.Since(generationStart)-generationTime, -1)
+		return nil
+	}
+
+	report := &graph.RunReport{Config: config, Graph: network, Metrics: metrics}
+	if config.MotifSize > 0 {
+		counts := network.CountMotifs(config.MotifSize)
+		report.Motifs = counts
+		var zscores map[string]float64
+		if config.MotifNullSamples > 0 {
+			zscores = network.MotifZScores(config.MotifSize, config.MotifNullSamples, rng)
+		}
+		codes := make([]string, 0, len(counts))
+		for code := range counts {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Printf("Size-%d motifs (%d classes):\n", config.MotifSize, len(codes))
+		for _, code := range codes {
+			if zscores != nil {
+				fmt.Printf("  %s: %d (z=%.2f)\n", code, counts[code], zscores[code])
+			} else {
+				fmt.Printf("  %s: %d\n", code, counts[code])
+			}
+		}
+	}
+
+	if *epidemicModel != "" {
+		curve, err := graph.RunEpidemic(network, *epidemicModel, *beta, *gamma, *initialInfected, *epidemicSteps, rng)
+		if err != nil {
+			return fmt.Errorf("running epidemic: %w", err)
+		}
+		if err := graph.WriteEpidemicCSV(curve, "epidemic.csv"); err != nil {
+			return fmt.Errorf("writing epidemic.csv: %w", err)
+		}
+		report.Files = append(report.Files, "epidemic.csv")
+		last := curve[len(curve)-1]
+		fmt.Printf("Epidemic (%s) ran %d steps: %d susceptible, %d infected, %d recovered. Curve saved to epidemic.csv\n",
+			*epidemicModel, last.Step, last.Susceptible, last.Infected, last.Recovered)
+	}
+
+	if *cascadeSeeds != "" {
+		thresholds := graph.CascadeThresholds(network.NumAgents, *threshold, rng)
+		seeds, err := graph.SelectSeeds(network, *numSeeds, *cascadeSeeds, rng)
+		if err != nil {
+			return fmt.Errorf("selecting cascade seeds: %w", err)
+		}
+		curve := graph.RunThresholdCascade(network, seeds, thresholds)
+		if err := graph.WriteCascadeCSV(curve, "cascade.csv"); err != nil {
+			return fmt.Errorf("writing cascade.csv: %w", err)
+		}
+		report.Files = append(report.Files, "cascade.csv")
+		fmt.Printf("Cascade (%s seeds) reached %d of %d nodes in %d rounds. Curve saved to cascade.csv\n",
+			*cascadeSeeds, curve[len(curve)-1], network.NumAgents, len(curve)-1)
+		// Rerun with the same thresholds under each seed selection to show sensitivity.
+		for _, strategy := range []string{"random", "degree"} {
+			seeds, _ := graph.SelectSeeds(network, *numSeeds, strategy, rng)
+			curve := graph.RunThresholdCascade(network, seeds, thresholds)
+			fmt.Printf("  %s seeds: final cascade size %d\n", strategy, curve[len(curve)-1])
+		}
+	}
+
+	if *influenceSeeds > 0 {
+		seeds, spreads, err := network.GreedyInfluenceSeeds(*influenceSeeds, *influenceModel, *influenceProb, *influenceTrials, rng)
+		if err != nil {
+			return fmt.Errorf("selecting influence seeds: %w", err)
+		}
+		for i, seed := range seeds {
+			fmt.Printf("Influence Maximization - Seed %d: node %d (expected spread %.1f)\n", i+1, seed, spreads[i])
+		}
+		fmt.Printf("Influence-maximizing seeds (%s): %v\n", *influenceModel, seeds)
+	}
+
+	centrality := make(map[string]map[int]float64)
+	pageRank, err := graph.PageRank(network, *damping, 1000)
+	if err != nil {
+		fmt.Println("Warning:", err)
+	}
+	centrality["pagerank"] = pageRank
+	fmt.Printf("Top nodes by PageRank (damping %g):\n", *damping)
+	for _, node := range graph.TopNodes(pageRank, 10) {
+		fmt.Printf("  %s: %.5f\n", network.Label(node), pageRank[node])
+	}
+	if *eigenvector {
+		scores, err := graph.EigenvectorCentrality(network, 1000)
+		if err != nil {
+			fmt.Println("Warning:", err)
+		}
+		centrality["eigenvector"] = scores
+		fmt.Println("Top nodes by eigenvector centrality:")
+		for _, node := range graph.TopNodes(scores, 10) {
+			fmt.Printf("  %s: %.5f\n", network.Label(node), scores[node])
+		}
+	}
+	if *closeness {
+		scores := graph.ClosenessCentrality(network)
+		centrality["closeness"] = scores
+		fmt.Println("Top nodes by closeness:")
+		for _, node := range graph.TopNodes(scores, 10) {
+			fmt.Printf("  %s: %.5f\n", network.Label(node), scores[node])
+		}
+	}
+	if *betweenness {
+		scores := graph.BetweennessCentrality(network)
+		centrality["betweenness"] = scores
+		fmt.Println("Top nodes by betweenness:")
+		for _, node := range graph.TopNodes(scores, 10) {
+			fmt.Printf("  %s: %.5f\n", network.Label(node), scores[node])
+		}
+	}
+
+	if *approxBetweenness > 0 {
+		scores := network.ApproxBetweenness(*approxBetweenness, rng)
+		samples := *approxBetweenness
+		if samples > network.NumAgents {
+			samples = network.NumAgents
+		}
+		fmt.Printf("Top nodes by approximate betweenness (%d sampled sources, error within ±%.4f at 95%%):\n",
+			samples, graph.BetweennessErrorBound(samples, network.NumAgents))
+		for _, node := range graph.TopNodes(scores, 10) {
+			fmt.Printf("  %s: %.5f\n", network.Label(node), scores[node])
+		}
+	}
+
+	if *pprSeeds != "" {
+		var seeds []int
+		for _, field := range strings.Split(*pprSeeds, ",") {
+			seed, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return fmt.Errorf("parsing -ppr-seeds: %w", err)
+			}
+			seeds = append(seeds, seed)
+		}
+		scores, err := graph.PersonalizedPageRank(network, seeds, *damping, 1000, config.EdgeWeights)
+		if err != nil {
+			fmt.Println("Warning:", err)
+		}
+		if scores != nil {
+			fmt.Printf("Top nodes by personalized PageRank from %v:\n", seeds)
+			for _, node := range graph.TopNodes(scores, 10) {
+				fmt.Printf("  %s: %.5f\n", network.Label(node), scores[node])
+			}
+		}
+	}
+
+	if *comparePath != "" {
+		other, err := graph.ReadNetwork(*comparePath)
+		if err != nil {
+			return fmt.Errorf("reading comparison network: %w", err)
+		}
+		if other.NumAgents != network.NumAgents {
+			fmt.Printf("Warning: %s has %d nodes, this network has %d\n", *comparePath, other.NumAgents, network.NumAgents)
+		}
+		fmt.Printf("Similarity to %s: %.4f directed, %.4f undirected\n",
+			*comparePath, graph.GraphSimilarity(network, other), graph.GraphSimilarityUndirected(network, other))
+		added, removed, weightChanges := graph.Diff(other, network)
+		fmt.Printf("Changes since %s: %s\n", *comparePath, graph.DiffSummary(added, removed, weightChanges))
+	}
+
+	writeStart := time.Now()
+	analysisTime := writeStart.Sub(generationStart) - generationTime
+
+	// Save the final network to network.json, unless it is being streamed as
+	// JSON lines, which exists to avoid building network.json's edge list.
+	if config.OutputFormat != "jsonl" {
+		path := networkFile("network.json")
+		if err := graph.SaveNetwork(network, path); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("Final network saved to %s\n", path)
+		report.Files = append(report.Files, path)
+	}
+
+	if config.OutputFormat != "json" {
+		export := graph.ExportNetwork
+		if config.Compress {
+			export = graph.ExportNetworkCompressed
+		}
+		file, err := export(network, config.OutputFormat)
+		if err != nil {
+			return fmt.Errorf("exporting network: %w", err)
+		}
+		fmt.Printf("Network exported to %s\n", file)
+		report.Files = append(report.Files, file)
+	}
+
+	if len(centrality) > 0 {
+		if err := graph.SaveCentrality("centrality.json", centrality); err != nil {
+			return fmt.Errorf("writing centrality.json: %w", err)
+		}
+		fmt.Println("Centrality scores saved to centrality.json")
+		report.Files = append(report.Files, "centrality.json")
+	}
+
+	if err := graph.SaveDegreeDistribution(network, "degrees.json"); err != nil {
+		return fmt.Errorf("writing degrees.json: %w", err)
+	}
+	fmt.Println("Degree distribution saved to degrees.json")
+	report.Files = append(report.Files, "degrees.json")
+
+	if config.MetricsOutput != "" {
+		if err := graph.SaveMetrics(network, config.MetricsOutput, config.Metrics); err != nil {
+			return fmt.Errorf("writing %s: %w", config.MetricsOutput, err)
+		}
+		fmt.Printf("Metrics saved to %s\n", config.MetricsOutput)
+		report.Files = append(report.Files, config.MetricsOutput)
+	}
+
+	if config.WriteUndirected {
+		undirected := network.Symmetrize()
+		path := networkFile("network_undirected.json")
+		if err := graph.SaveNetwork(undirected, path); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("Undirected projection (%d edges) saved to %s\n", len(undirected.Edges), path)
+		report.Files = append(report.Files, path)
+	}
+
+	if *reportPath != "" {
+		if err := graph.WriteReport(*reportPath, report); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+		fmt.Printf("Run report saved to %s\n", *reportPath)
+	}
+	printTiming(generationTime, analysisTime, time.Since(writeStart))
+	return nil
+}
+
+// printTiming reports, for -timing, how long generating (or loading) the
+// network, analyzing it and writing the output files took. writing is
+// negative when no files were written.
+func printTiming(generation, analysis, writing time.Duration) {
+	if !*timing {
+		return
+	}
+	phase := "generation"
+	if *inputPath != "" {
+		phase = "loading"
+	}
+	fmt.Printf("Timing: %s %s, analysis %s", phase, generation.Round(time.Millisecond), analysis.Round(time.Millisecond))
+	if writing >= 0 {
+		fmt.Printf(", writing output %s", writing.Round(time.Millisecond))
+	}
+	fmt.Println()
+}
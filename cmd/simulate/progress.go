@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressWidth is the number of cells in the progress bar.
+const progressWidth = 30
+
+// progressBar draws simulation progress on a single terminal line, rewriting
+// it with a carriage return after every step.
+type progressBar struct {
+	out     io.Writer
+	start   time.Time
+	drawn   bool // a partial bar line is on screen
+	lastLen int
+}
+
+func newProgressBar(out io.Writer) *progressBar {
+	return &progressBar{out: out, start: time.Now()}
+}
+
+// Update redraws the bar for a completed step. The line is ended once the
+// last step is reached.
+func (b *progressBar) Update(step, totalSteps, edgesSoFar int) {
+	if totalSteps <= 0 {
+		return
+	}
+	fraction := float64(step) / float64(totalSteps)
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * progressWidth)
+	line := fmt.Sprintf("[%s%s] %3.0f%% step %d/%d, %d edges",
+		strings.Repeat("#", filled), strings.Repeat(" ", progressWidth-filled),
+		fraction*100, step, totalSteps, edgesSoFar)
+	if elapsed := time.Since(b.start); step < totalSteps && fraction > 0 {
+		remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+		line += fmt.Sprintf(", ETA %s", remaining.Round(time.Second))
+	}
+	// Pad with spaces so a shorter line fully covers the previous one.
+	padding := ""
+	if len(line) < b.lastLen {
+		padding = strings.Repeat(" ", b.lastLen-len(line))
+	}
+	fmt.Fprintf(b.out, "\r%s%s", line, padding)
+	b.lastLen = len(line)
+	b.drawn = true
+	if step >= totalSteps {
+		b.Break()
+	}
+}
+
+// Break ends the bar's line so other output starts on a fresh one. The next
+// Update draws a new bar below it. It is safe on a nil *progressBar.
+func (b *progressBar) Break() {
+	if b != nil && b.drawn {
+		fmt.Fprintln(b.out)
+		b.drawn = false
+		b.lastLen = 0
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a file
+// or pipe, where carriage returns would pile up instead of redrawing.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressFunc returns the ProgressFunc for the -progress mode, and the bar it
+// draws (nil unless a bar is shown). "auto" draws a bar on a terminal and
+// prints one line per step otherwise.
+func progressFunc(mode string) (func(step, totalSteps, edgesSoFar int), *progressBar, error) {
+	if mode == "auto" {
+		mode = "lines"
+		if isTerminal(os.Stdout) {
+			mode = "bar"
+		}
+	}
+	switch mode {
+	case "bar":
+		bar := newProgressBar(os.Stdout)
+		return bar.Update, bar, nil
+	case "lines":
+		return func(step, totalSteps, edgesSoFar int) {
+			fmt.Printf("Step %d/%d: %d edges so far\n", step, totalSteps, edgesSoFar)
+		}, nil, nil
+	case "none":
+		return nil, nil, nil
+	}
+	return nil, nil, fmt.Errorf("in -progress: unknown mode %q (use auto, bar, lines or none)", mode)
+}
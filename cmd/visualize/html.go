@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/template"
+
+	"github.com/angrynarwhal/networks/graph"
+)
+
+// htmlFormat is the -format value that writes an interactive vis-network page
+// instead of running Graphviz.
+const htmlFormat = "html"
+
+// visNetworkURL is the vis-network build the HTML page loads. It is pinned so
+// a saved page keeps rendering the same way.
+const visNetworkURL = "https://unpkg.com/vis-network@9.1.9/standalone/umd/vis-network.min.js"
+
+// visNode and visEdge are the node and edge objects vis-network reads. Value
+// sets a node's size or an edge's width relative to the others.
+type visNode struct {
+	ID    int     `json:"id"`
+	Label string  `json:"label"`
+	Title string  `json:"title"`
+	Value int     `json:"value"`
+	Color string  `json:"color,omitempty"`
+	X     float64 `json:"x,omitempty"`
+	Y     float64 `json:"y,omitempty"`
+	Fixed bool    `json:"fixed,omitempty"`
+}
+
+type visEdge struct {
+	From  int     `json:"from"`
+	To    int     `json:"to"`
+	Value float64 `json:"value,omitempty"`
+	Title string  `json:"title,omitempty"`
+	Label string  `json:"label,omitempty"`
+}
+
+// visPositionScale converts the unit-square node positions of geometric
+// networks to vis-network canvas pixels.
+const visPositionScale = 1000.0
+
+// htmlPage renders the network. The data is embedded as JSON, so the page works
+// from a local file; only vis-network itself comes from the CDN, and a notice
+// replaces the graph when it can't be loaded.
+var htmlPage = template.Must(template.New("network.html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Network ({{.Nodes}} nodes, {{.Edges}} edges)</title>
+<script src="{{.Script}}"></script>
+<style>
+  html, body { margin: 0; height: 100%; font-family: sans-serif; }
+  #network { width: 100%; height: 100%; }
+  #offline { padding: 2em; }
+</style>
+</head>
+<body>
+<div id="network"></div>
+<script>
+var data = {{.Data}};
+if (typeof vis === "undefined") {
+  document.getElementById("network").innerHTML =
+    '<p id="offline">This page draws the network with vis-network, loaded from {{.Script}}. ' +
+    'It could not be loaded; connect to the internet and reload the page.</p>';
+} else {
+  new vis.Network(document.getElementById("network"), data, {
+    nodes: { shape: "dot", scaling: { min: 5, max: 30 } },
+    edges: {
+      arrows: { to: { enabled: {{.Directed}}, scaleFactor: 0.5 } },
+      scaling: { min: 1, max: 5 },
+      color: { inherit: "from", opacity: 0.6 }
+    },
+    physics: { stabilization: { iterations: 200 } },
+    interaction: { hover: true, tooltipDelay: 100 }
+  });
+}
+</script>
+</body>
+</html>
+`))
+
+// writeHTML writes an interactive page showing the kept nodes and the given
+// edges. Nodes are colored by group and sized by their degree among the drawn
+// edges; weighted edges are drawn wider the heavier they are.
+func writeHTML(path string, net *Network, keep []bool, edges []graph.Edge, palette []string, edgeLabels bool) error {
+	degree := make([]int, net.NumAgents)
+	for _, edge := range edges {
+		degree[edge.Source]++
+		degree[edge.Target]++
+	}
+	var nodes []visNode
+	for i := 0; i < net.NumAgents; i++ {
+		if !keep[i] {
+			continue
+		}
+		label := strconv.Itoa(i)
+		if i < len(net.Labels) {
+			label = net.Labels[i]
+		}
+		node := visNode{ID: i, Label: label, Value: degree[i], Title: fmt.Sprintf("%s: degree %d", label, degree[i])}
+		if group, ok := net.Groups[i]; ok {
+			node.Color = groupColor(group, palette)
+			node.Title += fmt.Sprintf(", group %d", group)
+		}
+		if pos, ok := net.Positions[i]; ok {
+			// Pin the node where the simulation placed it. Canvas y grows downward.
+			node.X, node.Y, node.Fixed = pos[0]*visPositionScale, -pos[1]*visPositionScale, true
+		}
+		nodes = append(nodes, node)
+	}
+	floatWeights := false
+	for _, edge := range edges {
+		if edge.FloatWeight != 0 {
+			floatWeights = true
+			break
+		}
+	}
+	visEdges := make([]visEdge, 0, len(edges))
+	for _, edge := range edges {
+		e := visEdge{From: edge.Source, To: edge.Target}
+		if w := drawnWeight(edge, floatWeights); w > 0 {
+			e.Value = w
+			e.Title = "weight " + strconv.FormatFloat(w, 'g', 3, 64)
+			if edgeLabels {
+				e.Label = strconv.FormatFloat(w, 'g', 3, 64)
+			}
+		}
+		visEdges = append(visEdges, e)
+	}
+	data, err := json.Marshal(struct {
+		Nodes []visNode `json:"nodes"`
+		Edges []visEdge `json:"edges"`
+	}{nodes, visEdges})
+	if err != nil {
+		return fmt.Errorf("marshalling network: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// text/template leaves Data as-is; json.Marshal already escapes <, > and &,
+	// so labels can't close the script element.
+	return htmlPage.Execute(file, struct {
+		Script       string
+		Data         string
+		Directed     bool
+		Nodes, Edges int
+	}{visNetworkURL, string(data), net.Directed, len(nodes), len(edges)})
+}
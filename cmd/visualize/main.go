@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/angrynarwhal/networks/graph"
+)
+
+// Network is the part of network.json the visualizer draws.
+type Network struct {
+	NumAgents int                `json:"num_agents"`
+	Directed  bool               `json:"directed"`
+	Edges     []graph.Edge       `json:"edges"`
+	Groups    map[int]int        `json:"groups,omitempty"`
+	Labels    []string           `json:"labels,omitempty"`
+	Positions map[int][2]float64 `json:"positions,omitempty"`
+}
+
+// graphvizMissing explains how to get Graphviz when its layout program (the
+// first argument) isn't on the PATH. The DOT file (the second) is already
+// written by then, so it can still be rendered elsewhere.
+const graphvizMissing = `Graphviz's %[1]s program was not found on your PATH, so no image was rendered.
+Install Graphviz and run this again:
+  macOS:          brew install graphviz
+  Debian/Ubuntu:  sudo apt install graphviz
+  Fedora:         sudo dnf install graphviz
+  Windows:        winget install graphviz (or choco install graphviz), then reopen the terminal
+The graph was still written to %[2]s; you can render it on another machine with
+  %[1]s -Tpng %[2]s -o network.png
+or paste it into an online Graphviz viewer. -format html needs no Graphviz at all.`
+
+// palettes are the built-in node color palettes for -palette. "colorblind" is
+// the Okabe-Ito palette, which stays distinguishable under common color blindness.
+var palettes = map[string][]string{
+	"default":    {"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"},
+	"colorblind": {"#E69F00", "#56B4E9", "#009E73", "#F0E442", "#0072B2", "#D55E00", "#CC79A7", "#999999"},
+}
+
+// parsePalette returns a built-in palette by name, or splits a comma-separated
+// list of Graphviz colors.
+func parsePalette(spec string) ([]string, error) {
+	if colors, ok := palettes[spec]; ok {
+		return colors, nil
+	}
+	var colors []string
+	for _, c := range strings.Split(spec, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			colors = append(colors, c)
+		}
+	}
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("palette %q is empty", spec)
+	}
+	return colors, nil
+}
+
+// groupColor maps a group id to a palette entry by its value alone, so a given
+// group gets the same color in every run no matter how many groups exist.
+func groupColor(group int, palette []string) string {
+	n := len(palette)
+	return palette[((group%n)+n)%n]
+}
+
+// filterNetwork returns which nodes to draw and the edges to draw between them.
+// A node is kept if its total degree is at least minDegree and, when group >= 0,
+// it belongs to that group. An edge is kept if both ends are kept and its weight
+// is above weightAbove (when weightAbove >= 0).
+func filterNetwork(net *Network, minDegree, group, weightAbove int) ([]bool, []graph.Edge) {
+	degree := make([]int, net.NumAgents)
+	for _, edge := range net.Edges {
+		degree[edge.Source]++
+		degree[edge.Target]++
+	}
+	keep := make([]bool, net.NumAgents)
+	for i := range keep {
+		keep[i] = degree[i] >= minDegree
+		if group >= 0 {
+			if g, ok := net.Groups[i]; !ok || g != group {
+				keep[i] = false
+			}
+		}
+	}
+	var edges []graph.Edge
+	for _, edge := range net.Edges {
+		if !keep[edge.Source] || !keep[edge.Target] {
+			continue
+		}
+		if weightAbove >= 0 && edge.Weight <= weightAbove {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+	return keep, edges
+}
+
+// imageFormats are the Graphviz output formats accepted by -format.
+var imageFormats = []string{"png", "svg", "pdf", "jpg", "gif", "ps"}
+
+// checkFormat returns an error unless format is one of imageFormats or htmlFormat.
+func checkFormat(format string) error {
+	if format == htmlFormat {
+		return nil
+	}
+	for _, f := range imageFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (supported: %s, %s)", format, strings.Join(imageFormats, ", "), htmlFormat)
+}
+
+// positionScale converts the unit-square node positions of geometric networks
+// to inches for neato.
+const positionScale = 10.0
+
+// Pen widths for the lightest and heaviest drawn edges.
+const (
+	minPenWidth = 1.0
+	maxPenWidth = 5.0
+)
+
+// penWidth maps weight linearly from [minWeight, maxWeight] onto
+// [minPenWidth, maxPenWidth]. If every weight is the same, edges get minPenWidth.
+func penWidth(weight, minWeight, maxWeight float64) float64 {
+	if maxWeight <= minWeight {
+		return minPenWidth
+	}
+	return minPenWidth + (maxPenWidth-minPenWidth)*(weight-minWeight)/(maxWeight-minWeight)
+}
+
+// drawnWeight is the weight an edge is drawn with: its continuous weight when
+// the network has them, otherwise its integer weight.
+func drawnWeight(edge graph.Edge, floatWeights bool) float64 {
+	if floatWeights {
+		return edge.FloatWeight
+	}
+	return float64(edge.Weight)
+}
+
+func main() {
+	minDegree := flag.Int("min-degree", 0, "only draw nodes with at least this total degree")
+	group := flag.Int("group", -1, "only draw nodes in this group and the edges between them")
+	weightAbove := flag.Int("weight-above", -1, "only draw edges with weight above this value")
+	edgeLabels := flag.Bool("edge-labels", false, "label weighted edges with their weight as well as drawing them thicker")
+	format := flag.String("format", "png", "image format passed to dot ("+strings.Join(imageFormats, ", ")+"), or "+htmlFormat+" for an interactive page that needs no Graphviz")
+	bipartite := flag.Bool("bipartite", false, "draw group 0 and group 1 (the two sets of a bipartite network) in two separate rows")
+	paletteSpec := flag.String("palette", "default", "group colors: 'default', 'colorblind', or a comma-separated list of colors")
+	flag.Parse()
+
+	if err := checkFormat(*format); err != nil {
+		log.Fatalf("Invalid -format: %v", err)
+	}
+	palette, err := parsePalette(*paletteSpec)
+	if err != nil {
+		log.Fatalf("Invalid -palette: %v", err)
+	}
+
+	// Read network.json, or the network.json.gz a compressed run writes instead.
+	path := "network.json"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, gzErr := os.Stat(path + ".gz"); gzErr == nil {
+			path += ".gz"
+		}
+	}
+	data, err := graph.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", path, err)
+	}
+
+	// Unmarshal JSON data into our Network struct. Files written before the
+	// "directed" field existed are directed.
+	net := Network{Directed: true}
+	err = json.Unmarshal(data, &net)
+	if err != nil {
+		log.Fatalf("Error parsing JSON: %v", err)
+	}
+
+	keep, edges := filterNetwork(&net, *minDegree, *group, *weightAbove)
+	keptNodes := 0
+	for _, k := range keep {
+		if k {
+			keptNodes++
+		}
+	}
+	fmt.Printf("Drawing %d of %d nodes and %d of %d edges.\n", keptNodes, net.NumAgents, len(edges), len(net.Edges))
+
+	if *format == htmlFormat {
+		htmlFile := "network.html"
+		if err := writeHTML(htmlFile, &net, keep, edges, palette, *edgeLabels); err != nil {
+			log.Fatalf("Error writing %s: %v", htmlFile, err)
+		}
+		fmt.Printf("Interactive visualization created: %s (open it in a browser; it loads vis-network from unpkg.com)\n", htmlFile)
+		return
+	}
+
+	// Build the DOT file content for a directed or undirected graph.
+	// This will include all nodes and each edge (with weights if applicable).
+	graphType, edgeOp := "digraph", "->"
+	if !net.Directed {
+		graphType, edgeOp = "graph", "--"
+	}
+	dot := graphType + " G {\n"
+	if len(net.Groups) > 0 {
+		dot += "  node [style=filled];\n"
+	}
+	// Create all nodes so that isolated nodes (without any edge) are also drawn.
+	for i := 0; i < net.NumAgents; i++ {
+		if !keep[i] {
+			continue
+		}
+		var attrs []string
+		if i < len(net.Labels) {
+			attrs = append(attrs, fmt.Sprintf("label=%q", net.Labels[i]))
+		}
+		if group, ok := net.Groups[i]; ok {
+			attrs = append(attrs, fmt.Sprintf("fillcolor=%q", groupColor(group, palette)))
+		}
+		if pos, ok := net.Positions[i]; ok {
+			// The trailing "!" pins the node so neato keeps the simulated layout.
+			attrs = append(attrs, fmt.Sprintf("pos=\"%.3f,%.3f!\"", pos[0]*positionScale, pos[1]*positionScale))
+		}
+		if len(attrs) > 0 {
+			dot += fmt.Sprintf("  %d [%s];\n", i, strings.Join(attrs, ", "))
+		} else {
+			dot += fmt.Sprintf("  %d;\n", i)
+		}
+	}
+	if *bipartite {
+		// rank=same keeps each set on one row, so dot draws the two sides apart
+		// with every edge running between the rows.
+		for side := 0; side < 2; side++ {
+			var ids []string
+			for i := 0; i < net.NumAgents; i++ {
+				if group, ok := net.Groups[i]; ok && group == side && keep[i] {
+					ids = append(ids, strconv.Itoa(i))
+				}
+			}
+			if len(ids) > 0 {
+				dot += fmt.Sprintf("  { rank=same; %s; }\n", strings.Join(ids, "; "))
+			}
+		}
+	}
+	// Add the edges. Weighted edges are drawn thicker the heavier they are,
+	// scaled between the lightest and heaviest edge being drawn.
+	floatWeights := false
+	for _, edge := range edges {
+		if edge.FloatWeight != 0 {
+			floatWeights = true
+			break
+		}
+	}
+	minWeight, maxWeight := 0.0, 0.0
+	for k, edge := range edges {
+		w := drawnWeight(edge, floatWeights)
+		if k == 0 || w < minWeight {
+			minWeight = w
+		}
+		if w > maxWeight {
+			maxWeight = w
+		}
+	}
+	for _, edge := range edges {
+		if w := drawnWeight(edge, floatWeights); w > 0 {
+			attrs := fmt.Sprintf("penwidth=%.2f", penWidth(w, minWeight, maxWeight))
+			if *edgeLabels {
+				attrs += fmt.Sprintf(", label=\"%s\"", strconv.FormatFloat(w, 'g', 3, 64))
+			}
+			dot += fmt.Sprintf("  %d %s %d [%s];\n", edge.Source, edgeOp, edge.Target, attrs)
+		} else {
+			dot += fmt.Sprintf("  %d %s %d;\n", edge.Source, edgeOp, edge.Target)
+		}
+	}
+	dot += "}\n"
+
+	// Write the DOT content to a file.
+	dotFile := "network.dot"
+	err = ioutil.WriteFile(dotFile, []byte(dot), 0644)
+	if err != nil {
+		log.Fatalf("Error writing %s: %v", dotFile, err)
+	}
+	fmt.Printf("DOT file '%s' created.\n", dotFile)
+
+	// Use Graphviz's dot tool to render the DOT file in the requested format,
+	// or neato when nodes have positions so they are drawn where they are.
+	// Make sure Graphviz is installed and on the system's PATH.
+	layout := "dot"
+	if len(net.Positions) > 0 {
+		layout = "neato"
+	}
+	if _, err := exec.LookPath(layout); err != nil {
+		log.Fatalf(graphvizMissing, layout, dotFile)
+	}
+	outImage := "network." + *format
+	cmd := exec.Command(layout, "-T"+*format, dotFile, "-o", outImage)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			log.Fatalf("Error running %s command: %v\n%s", layout, err, msg)
+		}
+		log.Fatalf("Error running %s command: %v", layout, err)
+	}
+	fmt.Printf("Network visualization created: %s\n", outImage)
+}
@@ -0,0 +1,3 @@
+module github.com/angrynarwhal/networks
+
+go 1.21
@@ -0,0 +1,563 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config holds all simulation parameters from config.json.
+type Config struct {
+	NumAgents             int           `json:"num_agents"`
+	LinkingStrategy       string        `json:"linking_strategy"` // “random”, “preferential_attachment”, “fitness”, “homophily”, “gnm”, “gnp”, “small_world”, “ring_lattice”, “complete”, “grid”, “bipartite”, “weighted_configuration”, “geometric”, and “configuration”
+	TimeSteps             int           `json:"time_steps"`
+	Dynamic               bool          `json:"dynamic"`
+	Directed              bool          `json:"directed"` // false stores each linked pair once (default true).
+	EdgeWeights           bool          `json:"edge_weights"`
+	OutputFormat          string        `json:"output_format"`          // Also export as "graphml", "gexf", "gml", "adjacency_csv", "adjacency_list", "pajek" or "edgelist_csv", or replace network.json with "jsonl" (default "json").
+	P                     float64       `json:"p"`                      // Used for random, gnp and bipartite linking.
+	EdgesPerStep          int           `json:"edges_per_step"`         // Used for preferential attachment.
+	HomophilyGroups       int           `json:"homophily_groups"`       // Number of groups for homophily.
+	PIn                   float64       `json:"p_in"`                   // Probability to link if same group.
+	POut                  float64       `json:"p_out"`                  // Probability to link if different groups.
+	MaxMemoryMB           int           `json:"max_memory_mb"`          // Abort when heap usage exceeds this many MB (0 disables).
+	MotifSize             int           `json:"motif_size"`             // Print motif counts of this size (3 or 4) after the run (0 disables).
+	MotifNullSamples      int           `json:"motif_null_samples"`     // Degree-preserving null graphs used for motif z-scores.
+	NodeLabelPrefix       string        `json:"node_label_prefix"`      // Label nodes as prefix+index, e.g. "agent_0".
+	NodeLabelsFile        string        `json:"node_labels_file"`       // File with one node label per line (overrides the prefix).
+	Pipeline              []StageConfig `json:"pipeline"`               // Optional: stages applied in order, each to the previous stage's graph.
+	Strategies            []string      `json:"strategies"`             // Optional: linking strategies applied in turn to the same nodes; shorthand for a pipeline of them with the top-level parameters.
+	GroupProbs            []float64     `json:"group_probs"`            // Optional: probability of each homophily group; overrides homophily_groups.
+	ColdStart             string        `json:"cold_start"`             // Preferential attachment start: "uniform", "complete_seed" or "attractiveness".
+	Attractiveness        float64       `json:"attractiveness"`         // Constant added to degrees under the "attractiveness" cold start.
+	WriteUndirected       bool          `json:"write_undirected"`       // Also write the symmetrized network to network_undirected.json.
+	FitnessDistribution   string        `json:"fitness_distribution"`   // Node fitness for the fitness strategy and fitness_enabled: "uniform" or "exponential".
+	StopOnConvergence     bool          `json:"stop_on_convergence"`    // Stop time-stepped strategies early once the convergence metric settles.
+	ConvergenceMetric     string        `json:"convergence_metric"`     // "edge_count", "average_degree" or "modularity".
+	ConvergenceTolerance  float64       `json:"convergence_tolerance"`  // Relative change per step treated as "no change".
+	ConvergencePatience   int           `json:"convergence_patience"`   // Consecutive calm steps required to stop.
+	MaxMultiplicity       int           `json:"max_multiplicity"`       // Cap on how many times a pair can be linked (edge weight); 0 disables.
+	DeathRate             float64       `json:"death_rate"`             // Probability per time step that each live node is removed with its edges (needs dynamic).
+	DegreeSequence        []int         `json:"degree_sequence"`        // Target degree per node for configuration and weighted_configuration.
+	StrengthSequence      []int         `json:"strength_sequence"`      // Target strength (total incident weight) per node for weighted_configuration.
+	K                     int           `json:"k"`                      // Small world and ring lattice: each node is linked to its k nearest ring neighbors.
+	Beta                  float64       `json:"beta"`                   // Small world: probability of rewiring each lattice edge.
+	Seed                  int64         `json:"seed"`                   // Random seed for reproducible runs (0 picks one from the clock and prints it).
+	NumEdges              int           `json:"num_edges"`              // Exact number of edges for the gnm strategy.
+	ChurnRate             float64       `json:"churn_rate"`             // Probability per time step that each edge is removed (needs dynamic).
+	SnapshotInterval      int           `json:"snapshot_interval"`      // Save network_t{step}.json every this many time steps (needs dynamic; 0 disables).
+	Radius                float64       `json:"radius"`                 // Geometric: nodes closer than this in the unit square are linked.
+	WeightDistribution    string        `json:"weight_distribution"`    // Draw a continuous float_weight per link from "uniform" or "exponential" ("" disables).
+	SeedNetwork           string        `json:"seed_network"`           // Network file to continue simulating from instead of starting empty.
+	AllowSelfLoops        bool          `json:"allow_self_loops"`       // Random, homophily, preferential attachment, fitness and configuration: allow a node to link to itself.
+	AllowMultiEdges       bool          `json:"allow_multi_edges"`      // Preferential attachment, fitness and configuration: keep repeated pairs, adding to the edge weight (needs edge_weights).
+	StatsOnly             bool          `json:"stats_only"`             // Print summary metrics only; write no output files (same as -stats).
+	GroupSizes            []int         `json:"group_sizes"`            // Optional: homophily group sizes, assigned to consecutive node ids; must sum to num_agents.
+	WeightDecay           float64       `json:"weight_decay"`           // Fraction of every edge weight lost at the start of each time step; edges falling below 1 are removed (needs dynamic and edge_weights).
+	FitnessEnabled        bool          `json:"fitness_enabled"`        // Preferential attachment: weight each node by a fitness drawn from fitness_distribution (the fitness strategy always does).
+	MetricsOutput         string        `json:"metrics_output"`         // Write summary metrics of the final network to this JSON file ("" disables).
+	Metrics               []string      `json:"metrics"`                // Metrics to write to metrics_output (default all of them).
+	Partition             []int         `json:"partition"`              // Bipartite: sizes of the two node sets, assigned to consecutive node ids (default an even split).
+	PSchedule             []float64     `json:"p_schedule"`             // Random: p for each time step in turn; the last value repeats once it runs out.
+	PInSchedule           []float64     `json:"p_in_schedule"`          // Homophily: p_in for each time step, like p_schedule.
+	POutSchedule          []float64     `json:"p_out_schedule"`         // Homophily: p_out for each time step, like p_schedule.
+	ScheduleInterpolation string        `json:"schedule_interpolation"` // "step" (default) uses schedule values one per step; "linear" spreads them evenly over time_steps and interpolates.
+	Compress              bool          `json:"compress"`               // Gzip network.json, snapshots and the output_format export, adding ".gz" to their names.
+	Rows                  int           `json:"rows"`                   // Rows of the grid strategy; rows × cols must equal num_agents.
+	Cols                  int           `json:"cols"`                   // Columns of the grid strategy.
+	GridDiagonals         bool          `json:"grid_diagonals"`         // Link grid nodes to all 8 neighbors instead of the 4 orthogonal ones.
+	GridTorus             bool          `json:"grid_torus"`             // Wrap the grid around at its edges (periodic boundaries).
+}
+
+// StageConfig describes one stage of a composite pipeline. Zero-valued
+// parameters fall back to the top-level config values.
+type StageConfig struct {
+	Strategy        string  `json:"strategy"` // A linking strategy, or "homophily_rewire" to modify the current graph.
+	TimeSteps       int     `json:"time_steps"`
+	P               float64 `json:"p"`
+	EdgesPerStep    int     `json:"edges_per_step"`
+	HomophilyGroups int     `json:"homophily_groups"`
+	PIn             float64 `json:"p_in"`
+	POut            float64 `json:"p_out"`
+	RewireFraction  float64 `json:"rewire_fraction"` // Fraction of edges rewired by "homophily_rewire".
+}
+
+// stageConfig returns a copy of config with the stage's parameters applied.
+func stageConfig(config *Config, stage StageConfig) *Config {
+	c := *config
+	c.LinkingStrategy = stage.Strategy
+	c.Pipeline = nil
+	if stage.TimeSteps != 0 {
+		c.TimeSteps = stage.TimeSteps
+	}
+	if stage.P != 0 {
+		c.P = stage.P
+	}
+	if stage.EdgesPerStep != 0 {
+		c.EdgesPerStep = stage.EdgesPerStep
+	}
+	if stage.HomophilyGroups != 0 {
+		c.HomophilyGroups = stage.HomophilyGroups
+	}
+	if stage.PIn != 0 {
+		c.PIn = stage.PIn
+	}
+	if stage.POut != 0 {
+		c.POut = stage.POut
+	}
+	return &c
+}
+
+// linkingStrategies lists the values linking_strategy accepts, in the order
+// error messages name them.
+var linkingStrategies = []string{"random", "preferential_attachment", "fitness", "homophily", "gnm", "gnp", "small_world", "ring_lattice", "complete", "grid", "bipartite", "weighted_configuration", "geometric", "configuration"}
+
+// knownStrategy reports whether name is one of linkingStrategies.
+func knownStrategy(name string) bool {
+	for _, s := range linkingStrategies {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConfig reads the configuration from a JSON file. The file is first checked
+// against the config schema (see ValidateConfig), so keys that don't match a
+// Config field are rejected and a misspelled setting isn't silently ignored.
+// Defaults only apply to keys that are absent: an explicit "p": 0 means 0.
+// Every invalid setting is reported, one per line, in a single error.
+func LoadConfig(configPath string) (*Config, error) {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateConfig(data); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return nil, fmt.Errorf("parsing %s: %w", configPath, err)
+		}
+		return nil, err
+	}
+	// Fields that default to true must be set before decoding, since a missing
+	// key leaves them untouched.
+	config := Config{Directed: true}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", configPath, err)
+	}
+	var keys map[string]json.RawMessage
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", configPath, err)
+	}
+	set := func(key string) bool {
+		_, ok := keys[key]
+		return ok
+	}
+	var problems []string
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	probability := func(name string, value float64) {
+		if value < 0 || value > 1 {
+			invalid("%s must be in [0, 1], got %g", name, value)
+		}
+	}
+
+	// A strategies list is a pipeline whose stages all use the top-level
+	// parameters.
+	if len(config.Strategies) > 0 {
+		if len(config.Pipeline) > 0 {
+			invalid("strategies and pipeline can't both be set (use pipeline stages to give each strategy its own parameters)")
+		} else {
+			for _, strategy := range config.Strategies {
+				config.Pipeline = append(config.Pipeline, StageConfig{Strategy: strategy})
+			}
+		}
+	}
+	// uses reports whether the run builds with any of the given strategies,
+	// either as linking_strategy or in a pipeline stage, so their settings are
+	// defaulted and checked however the strategy is reached.
+	uses := func(strategies ...string) bool {
+		for _, strategy := range strategies {
+			if len(config.Pipeline) == 0 && config.LinkingStrategy == strategy {
+				return true
+			}
+			for _, stage := range config.Pipeline {
+				if stage.Strategy == strategy {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if len(config.Pipeline) == 0 && !knownStrategy(config.LinkingStrategy) {
+		invalid("unknown linking_strategy '%s' (expected %s or %s)", config.LinkingStrategy, strings.Join(linkingStrategies[:len(linkingStrategies)-1], ", "), linkingStrategies[len(linkingStrategies)-1])
+	}
+	for i, stage := range config.Pipeline {
+		if stage.Strategy != "homophily_rewire" && !knownStrategy(stage.Strategy) {
+			invalid("pipeline stage %d: unknown strategy '%s' (expected a linking strategy or homophily_rewire)", i+1, stage.Strategy)
+		}
+		if stage.TimeSteps < 0 {
+			invalid("pipeline stage %d: time_steps must not be negative, got %d", i+1, stage.TimeSteps)
+		}
+		probability(fmt.Sprintf("pipeline stage %d: p", i+1), stage.P)
+		probability(fmt.Sprintf("pipeline stage %d: p_in", i+1), stage.PIn)
+		probability(fmt.Sprintf("pipeline stage %d: p_out", i+1), stage.POut)
+		probability(fmt.Sprintf("pipeline stage %d: rewire_fraction", i+1), stage.RewireFraction)
+	}
+	if uses("configuration", "weighted_configuration") {
+		if !set("num_agents") {
+			config.NumAgents = len(config.DegreeSequence)
+		}
+		if config.NumAgents != len(config.DegreeSequence) {
+			invalid("num_agents is %d but degree_sequence has %d entries", config.NumAgents, len(config.DegreeSequence))
+		}
+	}
+	if uses("grid") && !set("num_agents") {
+		config.NumAgents = config.Rows * config.Cols
+	}
+	if uses("configuration") {
+		if err := checkDegreeSequence(config.DegreeSequence); err != nil {
+			invalid("%v", err)
+		}
+	}
+	if uses("weighted_configuration") {
+		if !config.EdgeWeights {
+			invalid("weighted_configuration needs edge_weights")
+		}
+		if err := checkStrengthSequence(config.DegreeSequence, config.StrengthSequence); err != nil {
+			invalid("%v", err)
+		}
+	}
+	if len(config.Pipeline) == 0 {
+		switch config.LinkingStrategy {
+		case "random", "homophily", "preferential_attachment", "fitness", "configuration":
+		default:
+			if config.AllowSelfLoops {
+				invalid("allow_self_loops is not supported by the %s strategy (use random, homophily, preferential_attachment, fitness or configuration)", config.LinkingStrategy)
+			}
+		}
+		switch config.LinkingStrategy {
+		case "preferential_attachment", "fitness":
+		default:
+			if config.FitnessEnabled {
+				invalid("fitness_enabled is not supported by the %s strategy (use preferential_attachment)", config.LinkingStrategy)
+			}
+		}
+		switch config.LinkingStrategy {
+		case "preferential_attachment", "fitness", "configuration":
+		default:
+			if config.AllowMultiEdges {
+				invalid("allow_multi_edges is not supported by the %s strategy (use preferential_attachment, fitness or configuration; random and homophily count repeated links in edge_weights already)", config.LinkingStrategy)
+			}
+		}
+	}
+	if config.AllowMultiEdges && !config.EdgeWeights {
+		invalid("allow_multi_edges needs edge_weights, which record how many times a pair was linked")
+	}
+	// Set defaults for unspecified parameters.
+	if !set("num_agents") && config.NumAgents == 0 {
+		config.NumAgents = 100
+	}
+	if !set("time_steps") {
+		config.TimeSteps = 10
+	}
+	if !set("p") {
+		config.P = 0.05
+	}
+	if !set("edges_per_step") {
+		config.EdgesPerStep = 1
+	}
+	if !set("homophily_groups") {
+		config.HomophilyGroups = 2
+	}
+	if !set("p_in") {
+		config.PIn = 0.1
+	}
+	if !set("p_out") {
+		config.POut = 0.01
+	}
+	if !set("k") {
+		config.K = 4
+	}
+	if !set("beta") {
+		config.Beta = 0.1
+	}
+	if !set("radius") {
+		config.Radius = 0.1
+	}
+	if !set("attractiveness") {
+		config.Attractiveness = 1
+	}
+	if config.NumAgents < 1 {
+		invalid("num_agents must be positive, got %d", config.NumAgents)
+	}
+	if config.TimeSteps < 1 {
+		invalid("time_steps must be positive, got %d", config.TimeSteps)
+	}
+	probability("p", config.P)
+	probability("p_in", config.PIn)
+	probability("p_out", config.POut)
+	if config.HomophilyGroups < 1 && len(config.GroupProbs) == 0 && len(config.GroupSizes) == 0 {
+		invalid("homophily_groups must be positive, got %d", config.HomophilyGroups)
+	}
+	if uses("preferential_attachment", "fitness") {
+		if config.EdgesPerStep < 1 || config.EdgesPerStep >= config.NumAgents {
+			invalid("edges_per_step must be between 1 and num_agents-1 (%d), got %d", config.NumAgents-1, config.EdgesPerStep)
+		}
+	}
+	if uses("gnm") {
+		maxEdges := config.NumAgents * (config.NumAgents - 1)
+		if !config.Directed {
+			maxEdges /= 2
+		}
+		if config.NumEdges < 1 || config.NumEdges > maxEdges {
+			invalid("num_edges must be between 1 and %d for %d nodes, got %d", maxEdges, config.NumAgents, config.NumEdges)
+		}
+	}
+	if uses("small_world", "ring_lattice") {
+		if config.K < 2 || config.K%2 != 0 || config.K >= config.NumAgents {
+			invalid("k must be an even number between 2 and num_agents-1, got %d", config.K)
+		}
+	}
+	if uses("small_world") {
+		probability("beta", config.Beta)
+	}
+	if uses("bipartite") {
+		if len(config.Partition) == 0 {
+			config.Partition = []int{config.NumAgents / 2, config.NumAgents - config.NumAgents/2}
+		}
+		sum := 0
+		for _, size := range config.Partition {
+			sum += size
+		}
+		if len(config.Partition) != 2 || config.Partition[0] < 1 || config.Partition[1] < 1 {
+			invalid("partition must list two positive set sizes, got %v", config.Partition)
+		} else if sum != config.NumAgents {
+			invalid("partition sizes %v sum to %d, not num_agents %d", config.Partition, sum, config.NumAgents)
+		}
+	} else if len(config.Partition) > 0 {
+		invalid("partition is only used by the bipartite strategy")
+	}
+	if uses("grid") {
+		if config.Rows < 1 || config.Cols < 1 {
+			invalid("rows and cols must be positive, got %d and %d", config.Rows, config.Cols)
+		} else if config.Rows*config.Cols != config.NumAgents {
+			invalid("a %d×%d grid has %d nodes, not num_agents %d", config.Rows, config.Cols, config.Rows*config.Cols, config.NumAgents)
+		}
+		if config.GridTorus && (config.Rows < 3 || config.Cols < 3) {
+			invalid("grid_torus needs at least 3 rows and cols, got %d and %d", config.Rows, config.Cols)
+		}
+	} else if config.Rows != 0 || config.Cols != 0 || config.GridDiagonals || config.GridTorus {
+		invalid("rows, cols, grid_diagonals and grid_torus are only used by the grid strategy")
+	}
+	if uses("geometric") && (config.Radius <= 0 || config.Radius > math.Sqrt2) {
+		invalid("radius must be in (0, %g], got %g", math.Sqrt2, config.Radius)
+	}
+	// Output formats are case-insensitive ("GraphML" works). network.json is
+	// written alongside every format except jsonl.
+	config.OutputFormat = strings.ToLower(config.OutputFormat)
+	if config.OutputFormat == "" {
+		config.OutputFormat = "json"
+	}
+	if _, ok := exporters[config.OutputFormat]; !ok && config.OutputFormat != "json" {
+		invalid("unknown output_format '%s' (expected json, jsonl, graphml, gexf, gml, adjacency_csv, adjacency_list, pajek or edgelist_csv)", config.OutputFormat)
+	}
+	if len(config.Metrics) > 0 && config.MetricsOutput == "" {
+		invalid("metrics lists what metrics_output writes, but metrics_output is not set")
+	}
+	known := SummaryMetricNames()
+	for _, name := range config.Metrics {
+		found := false
+		for _, k := range known {
+			found = found || k == name
+		}
+		if !found {
+			invalid("unknown metric '%s' in metrics (expected %s)", name, strings.Join(known, ", "))
+		}
+	}
+	switch config.ColdStart {
+	case "":
+		config.ColdStart = "complete_seed"
+	case "uniform", "complete_seed", "attractiveness":
+	default:
+		invalid("unknown cold_start '%s' (expected uniform, complete_seed or attractiveness)", config.ColdStart)
+	}
+	switch config.FitnessDistribution {
+	case "":
+		config.FitnessDistribution = "uniform"
+	case "uniform", "exponential":
+	default:
+		invalid("unknown fitness_distribution '%s' (expected uniform or exponential)", config.FitnessDistribution)
+	}
+	switch config.WeightDistribution {
+	case "", "uniform", "exponential":
+	default:
+		invalid("unknown weight_distribution '%s' (expected uniform or exponential)", config.WeightDistribution)
+	}
+	if config.DeathRate < 0 || config.DeathRate >= 1 {
+		invalid("death_rate must be in [0, 1), got %g", config.DeathRate)
+	}
+	if config.ChurnRate < 0 || config.ChurnRate >= 1 {
+		invalid("churn_rate must be in [0, 1), got %g", config.ChurnRate)
+	}
+	if config.WeightDecay < 0 || config.WeightDecay >= 1 {
+		invalid("weight_decay must be in [0, 1), got %g", config.WeightDecay)
+	}
+	if config.WeightDecay > 0 {
+		if !config.Dynamic {
+			invalid("weight_decay needs dynamic, since weights decay between time steps")
+		}
+		if !config.EdgeWeights {
+			invalid("weight_decay needs edge_weights, since it shrinks edge weights")
+		}
+		if len(config.Pipeline) == 0 && config.LinkingStrategy != "random" && config.LinkingStrategy != "homophily" {
+			invalid("weight_decay is only supported by the time-stepped random and homophily strategies")
+		}
+	}
+	schedules := []struct {
+		name     string
+		values   []float64
+		strategy string
+	}{{"p_schedule", config.PSchedule, "random"}, {"p_in_schedule", config.PInSchedule, "homophily"}, {"p_out_schedule", config.POutSchedule, "homophily"}}
+	scheduled := false
+	for _, s := range schedules {
+		if len(s.values) == 0 {
+			continue
+		}
+		scheduled = true
+		for i, v := range s.values {
+			probability(fmt.Sprintf("%s[%d]", s.name, i), v)
+		}
+		if len(config.Pipeline) > 0 || config.LinkingStrategy != s.strategy {
+			invalid("%s is only supported by the %s strategy (without a pipeline)", s.name, s.strategy)
+		}
+	}
+	if scheduled && !config.Dynamic {
+		invalid("link probability schedules need dynamic, since they change between time steps")
+	}
+	switch config.ScheduleInterpolation {
+	case "":
+		config.ScheduleInterpolation = "step"
+	case "step", "linear":
+	default:
+		invalid("unknown schedule_interpolation '%s' (expected step or linear)", config.ScheduleInterpolation)
+	}
+	if config.SnapshotInterval < 0 {
+		invalid("snapshot_interval must not be negative, got %d", config.SnapshotInterval)
+	}
+	if config.SnapshotInterval > 0 && !config.Dynamic {
+		invalid("snapshot_interval needs dynamic, since snapshots are taken between time steps")
+	}
+	if config.ChurnRate > 0 && !config.Dynamic {
+		invalid("churn_rate needs dynamic, since edges decay between time steps")
+	}
+	if config.DeathRate > 0 && !config.Dynamic {
+		invalid("death_rate needs dynamic, since nodes are removed between time steps")
+	}
+	if config.MaxMultiplicity < 0 {
+		invalid("max_multiplicity must not be negative, got %d", config.MaxMultiplicity)
+	}
+	if config.MaxMultiplicity > 0 && !config.EdgeWeights {
+		invalid("max_multiplicity needs edge_weights, since repeated links are only counted as weights")
+	}
+	if config.StopOnConvergence {
+		switch config.ConvergenceMetric {
+		case "":
+			config.ConvergenceMetric = "edge_count"
+		case "edge_count", "average_degree":
+		case "modularity":
+			if config.LinkingStrategy != "homophily" {
+				invalid("convergence_metric 'modularity' needs group membership; use it with the homophily strategy")
+			}
+		default:
+			invalid("unknown convergence_metric '%s' (expected edge_count, average_degree or modularity)", config.ConvergenceMetric)
+		}
+		if config.ConvergenceTolerance == 0 {
+			config.ConvergenceTolerance = 0.001
+		}
+		if config.ConvergencePatience == 0 {
+			config.ConvergencePatience = 3
+		}
+	}
+	if len(config.GroupProbs) > 0 {
+		sum := 0.0
+		negative := false
+		for _, prob := range config.GroupProbs {
+			if prob < 0 {
+				negative = true
+			}
+			sum += prob
+		}
+		if negative {
+			invalid("group_probs must not contain negative probabilities, got %v", config.GroupProbs)
+		} else if math.Abs(sum-1) > 1e-9 {
+			invalid("group_probs must sum to 1, got %g", sum)
+		}
+		config.HomophilyGroups = len(config.GroupProbs)
+	}
+	if len(config.GroupSizes) > 0 {
+		sum := 0
+		negative := false
+		for _, size := range config.GroupSizes {
+			if size < 0 {
+				negative = true
+			}
+			sum += size
+		}
+		if len(config.GroupProbs) > 0 {
+			invalid("group_sizes and group_probs can't both be set")
+		} else if negative {
+			invalid("group_sizes must not contain negative sizes, got %v", config.GroupSizes)
+		} else if sum != config.NumAgents {
+			invalid("group_sizes must sum to num_agents (%d), got %d", config.NumAgents, sum)
+		}
+		config.HomophilyGroups = len(config.GroupSizes)
+	}
+	if err := problemsError(problems); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// NodeLabels builds the node labels requested by the config, or returns nil when
+// nodes should keep their integer ids as labels.
+func NodeLabels(config *Config) ([]string, error) {
+	if config.NodeLabelsFile != "" {
+		data, err := ioutil.ReadFile(config.NodeLabelsFile)
+		if err != nil {
+			return nil, err
+		}
+		var labels []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				labels = append(labels, line)
+			}
+		}
+		if len(labels) != config.NumAgents {
+			return nil, fmt.Errorf("%s has %d labels, expected one per agent (%d)", config.NodeLabelsFile, len(labels), config.NumAgents)
+		}
+		return labels, nil
+	}
+	if config.NodeLabelPrefix != "" {
+		labels := make([]string, config.NumAgents)
+		for i := range labels {
+			labels[i] = config.NodeLabelPrefix + strconv.Itoa(i)
+		}
+		return labels, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadConfigString writes a config to a temporary file and loads it.
+func loadConfigString(t *testing.T, contents string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path)
+}
+
+// TestLoadConfigStrategies checks that strategies listed in "strategies" get
+// the same defaults and checks as linking_strategy.
+func TestLoadConfigStrategies(t *testing.T) {
+	config, err := loadConfigString(t, `{"strategies": ["grid", "random"], "rows": 4, "cols": 5}`)
+	if err != nil {
+		t.Fatalf("grid stage: %v", err)
+	}
+	if config.NumAgents != 20 || len(config.Pipeline) != 2 {
+		t.Errorf("grid stage: got %d agents and %d stages, want 20 and 2", config.NumAgents, len(config.Pipeline))
+	}
+
+	config, err = loadConfigString(t, `{"strategies": ["bipartite", "random"], "num_agents": 10}`)
+	if err != nil {
+		t.Fatalf("bipartite stage: %v", err)
+	}
+	if len(config.Partition) != 2 || config.Partition[0]+config.Partition[1] != 10 {
+		t.Errorf("bipartite stage: partition %v was not defaulted to an even split", config.Partition)
+	}
+
+	config, err = loadConfigString(t, `{"strategies": ["configuration", "random"], "degree_sequence": [1, 1, 2, 2]}`)
+	if err != nil {
+		t.Fatalf("configuration stage: %v", err)
+	}
+	if config.NumAgents != 4 {
+		t.Errorf("configuration stage: num_agents = %d, want 4 from degree_sequence", config.NumAgents)
+	}
+
+	_, err = loadConfigString(t, `{"strategies": ["configuration", "random"], "num_agents": 100, "degree_sequence": [1, 1, 2, 2]}`)
+	if err == nil || !strings.Contains(err.Error(), "degree_sequence has 4 entries") {
+		t.Errorf("configuration stage with mismatched num_agents: got %v", err)
+	}
+
+	_, err = loadConfigString(t, `{"strategies": ["random"], "pipeline": [{"strategy": "random"}]}`)
+	if err == nil {
+		t.Error("strategies with pipeline: expected an error")
+	}
+}
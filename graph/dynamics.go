@@ -0,0 +1,267 @@
+package graph
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// EpidemicStep records the compartment sizes after one step of an epidemic.
+type EpidemicStep struct {
+	Step        int
+	Susceptible int
+	Infected    int
+	Recovered   int
+}
+
+// RunEpidemic simulates an SIR or SIS epidemic spreading along directed edges.
+// Each step every infected node infects each susceptible out-neighbor with
+// probability beta, then recovers with probability gamma (back to susceptible
+// under SIS). It stops when no node is infected or after maxSteps steps.
+func RunEpidemic(g *Graph, model string, beta, gamma float64, initialInfected, maxSteps int, rng *rand.Rand) ([]EpidemicStep, error) {
+	if model != "sir" && model != "sis" {
+		return nil, fmt.Errorf("unknown epidemic model '%s' (expected sir or sis)", model)
+	}
+	if initialInfected < 1 || initialInfected > g.NumAgents {
+		return nil, fmt.Errorf("initial infected count %d must be between 1 and %d", initialInfected, g.NumAgents)
+	}
+	const (
+		susceptible = iota
+		infected
+		recovered
+	)
+	adj := g.outAdjacency()
+	state := make([]int, g.NumAgents)
+	for _, i := range rng.Perm(g.NumAgents)[:initialInfected] {
+		state[i] = infected
+	}
+	record := func(step int) EpidemicStep {
+		counts := EpidemicStep{Step: step}
+		for _, s := range state {
+			switch s {
+			case susceptible:
+				counts.Susceptible++
+			case infected:
+				counts.Infected++
+			case recovered:
+				counts.Recovered++
+			}
+		}
+		return counts
+	}
+	curve := []EpidemicStep{record(0)}
+	for step := 1; step <= maxSteps && curve[len(curve)-1].Infected > 0; step++ {
+		next := append([]int(nil), state...)
+		for i, s := range state {
+			if s != infected {
+				continue
+			}
+			for _, j := range adj[i] {
+				if state[j] == susceptible && rng.Float64() < beta {
+					next[j] = infected
+				}
+			}
+			if rng.Float64() < gamma {
+				if model == "sir" {
+					next[i] = recovered
+				} else {
+					next[i] = susceptible
+				}
+			}
+		}
+		state = next
+		curve = append(curve, record(step))
+	}
+	return curve, nil
+}
+
+// WriteEpidemicCSV writes the infection curve as step,susceptible,infected,recovered rows.
+func WriteEpidemicCSV(curve []EpidemicStep, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	w.Write([]string{"step", "susceptible", "infected", "recovered"})
+	for _, c := range curve {
+		w.Write([]string{strconv.Itoa(c.Step), strconv.Itoa(c.Susceptible), strconv.Itoa(c.Infected), strconv.Itoa(c.Recovered)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// SelectSeeds picks k seed nodes either uniformly at random ("random") or as the
+// k nodes with the highest total degree ("degree"), breaking ties by node id.
+func SelectSeeds(g *Graph, k int, strategy string, rng *rand.Rand) ([]int, error) {
+	if k < 1 || k > g.NumAgents {
+		return nil, fmt.Errorf("seed count %d must be between 1 and %d", k, g.NumAgents)
+	}
+	switch strategy {
+	case "random":
+		return rng.Perm(g.NumAgents)[:k], nil
+	case "degree":
+		degree := g.degrees()
+		nodes := make([]int, g.NumAgents)
+		for i := range nodes {
+			nodes[i] = i
+		}
+		sort.SliceStable(nodes, func(a, b int) bool { return degree[nodes[a]] > degree[nodes[b]] })
+		return nodes[:k], nil
+	default:
+		return nil, fmt.Errorf("unknown seed selection '%s' (expected random or degree)", strategy)
+	}
+}
+
+// RunThresholdCascade runs the linear threshold model: a node adopts once the
+// weighted fraction of its in-neighbors that have adopted reaches its threshold.
+// It returns the cumulative number of adopters after each round, starting with
+// the seeds at round 0, and stops when a round adds no adopters.
+func RunThresholdCascade(g *Graph, seeds []int, thresholds []float64) []int {
+	in := g.inAdjacency()
+	adopted := make([]bool, g.NumAgents)
+	for _, s := range seeds {
+		adopted[s] = true
+	}
+	curve := []int{len(seeds)}
+	for {
+		var newAdopters []int
+		for i := 0; i < g.NumAgents; i++ {
+			if adopted[i] || len(in[i]) == 0 {
+				continue
+			}
+			total, active := 0.0, 0.0
+			for _, n := range in[i] {
+				total += n.Weight
+				if adopted[n.Node] {
+					active += n.Weight
+				}
+			}
+			if active/total >= thresholds[i] {
+				newAdopters = append(newAdopters, i)
+			}
+		}
+		if len(newAdopters) == 0 {
+			return curve
+		}
+		for _, i := range newAdopters {
+			adopted[i] = true
+		}
+		curve = append(curve, curve[len(curve)-1]+len(newAdopters))
+	}
+}
+
+// CascadeThresholds returns a per-node threshold: the fixed value if it's
+// positive, otherwise a uniform random draw from (0, 1].
+func CascadeThresholds(numAgents int, fixed float64, rng *rand.Rand) []float64 {
+	thresholds := make([]float64, numAgents)
+	for i := range thresholds {
+		if fixed > 0 {
+			thresholds[i] = fixed
+		} else {
+			thresholds[i] = 1 - rng.Float64()
+		}
+	}
+	return thresholds
+}
+
+// WriteCascadeCSV writes the adoption curve as round,adopters rows.
+func WriteCascadeCSV(curve []int, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	w.Write([]string{"round", "adopters"})
+	for round, adopters := range curve {
+		w.Write([]string{strconv.Itoa(round), strconv.Itoa(adopters)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// independentCascade runs one independent cascade from the seeds: every newly
+// activated node gets a single chance to activate each inactive out-neighbor
+// with probability prob. It returns the number of activated nodes.
+func independentCascade(adj [][]int, seeds []int, prob float64, rng *rand.Rand) int {
+	active := make([]bool, len(adj))
+	frontier := make([]int, 0, len(seeds))
+	for _, s := range seeds {
+		if !active[s] {
+			active[s] = true
+			frontier = append(frontier, s)
+		}
+	}
+	count := len(frontier)
+	for len(frontier) > 0 {
+		var next []int
+		for _, i := range frontier {
+			for _, j := range adj[i] {
+				if !active[j] && rng.Float64() < prob {
+					active[j] = true
+					next = append(next, j)
+				}
+			}
+		}
+		count += len(next)
+		frontier = next
+	}
+	return count
+}
+
+// expectedSpread estimates the expected cascade size from seeds by averaging
+// 'trials' Monte Carlo runs of the given model ("ic" or "lt").
+func (g *Graph) expectedSpread(seeds []int, model string, prob float64, trials int, adj [][]int, rng *rand.Rand) float64 {
+	total := 0
+	for t := 0; t < trials; t++ {
+		if model == "ic" {
+			total += independentCascade(adj, seeds, prob, rng)
+		} else {
+			curve := RunThresholdCascade(g, seeds, CascadeThresholds(g.NumAgents, 0, rng))
+			total += curve[len(curve)-1]
+		}
+	}
+	return float64(total) / float64(trials)
+}
+
+// GreedyInfluenceSeeds picks k seeds that greedily maximize the expected cascade
+// size (Kempe, Kleinberg & Tardos). Each round adds the node with the largest
+// Monte Carlo estimate of spread given the seeds chosen so far. The model is
+// "ic" (independent cascade with activation probability prob) or "lt" (linear
+// threshold with random thresholds; prob is ignored). Cost is roughly
+// k * NumAgents * trials cascades, so keep trials modest on large graphs.
+// spreads[i] is the estimated spread of the first i+1 seeds, so successive
+// differences are each seed's marginal gain.
+func (g *Graph) GreedyInfluenceSeeds(k int, model string, prob float64, trials int, rng *rand.Rand) (seeds []int, spreads []float64, err error) {
+	if model != "ic" && model != "lt" {
+		return nil, nil, fmt.Errorf("unknown influence model '%s' (expected ic or lt)", model)
+	}
+	if k < 1 || k > g.NumAgents {
+		return nil, nil, fmt.Errorf("seed count %d must be between 1 and %d", k, g.NumAgents)
+	}
+	if trials < 1 {
+		return nil, nil, fmt.Errorf("trials must be positive, got %d", trials)
+	}
+	adj := g.outAdjacency()
+	chosen := make(map[int]bool)
+	for len(seeds) < k {
+		best, bestSpread := -1, -1.0
+		for candidate := 0; candidate < g.NumAgents; candidate++ {
+			if chosen[candidate] {
+				continue
+			}
+			spread := g.expectedSpread(append(seeds, candidate), model, prob, trials, adj, rng)
+			if spread > bestSpread {
+				best, bestSpread = candidate, spread
+			}
+		}
+		chosen[best] = true
+		seeds = append(seeds, best)
+		spreads = append(spreads, bestSpread)
+	}
+	return seeds, spreads, nil
+}
@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestGreedyInfluenceSeeds checks that the hub of a directed star is picked
+// first and that the returned spreads are those of the growing seed sets.
+func TestGreedyInfluenceSeeds(t *testing.T) {
+	g := testGraph(5, true, [2]int{2, 0}, [2]int{2, 1}, [2]int{2, 3}, [2]int{2, 4})
+	seeds, spreads, err := g.GreedyInfluenceSeeds(2, "ic", 1, 10, rand.New(rand.NewSource(209)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seeds) != 2 || len(spreads) != 2 {
+		t.Fatalf("got seeds %v and spreads %v, want two of each", seeds, spreads)
+	}
+	if seeds[0] != 2 {
+		t.Errorf("first seed %d, want the hub 2", seeds[0])
+	}
+	// With activation probability 1 the hub reaches every node, and a second
+	// seed adds nothing.
+	if spreads[0] != 5 || spreads[1] != 5 {
+		t.Errorf("spreads %v, want [5 5]", spreads)
+	}
+}
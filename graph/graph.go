@@ -0,0 +1,688 @@
+// Package graph generates, analyzes and reads and writes agent networks. The
+// simulate and visualize commands under cmd/ are built on it.
+package graph
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Edge represents an edge in the network, from Source to Target in a directed
+// graph. In an undirected graph Source is always the smaller node id.
+// Weight counts the links formed between the pair when edge weights are on;
+// FloatWeight is the continuous strength drawn for those links when a weight
+// distribution is configured, and 0 otherwise.
+// Attributes holds optional extra properties (creation time, type, sign, ...)
+// so new edge data doesn't require new struct fields.
+type Edge struct {
+	Source      int                    `json:"source"`
+	Target      int                    `json:"target"`
+	Weight      int                    `json:"weight"`
+	FloatWeight float64                `json:"float_weight,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// EdgeKey identifies a node pair in Graph.Edges. Undirected graphs store each
+// pair once, with Source the smaller id. It marshals to text as "source_target",
+// so a Graph encoded as JSON keeps its edge map keys readable.
+type EdgeKey struct {
+	Source, Target int
+}
+
+// MarshalText encodes k as "source_target".
+func (k EdgeKey) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(k.Source) + "_" + strconv.Itoa(k.Target)), nil
+}
+
+// UnmarshalText parses a key written by MarshalText.
+func (k *EdgeKey) UnmarshalText(text []byte) error {
+	source, target, found := strings.Cut(string(text), "_")
+	if !found {
+		return fmt.Errorf("invalid edge key %q", text)
+	}
+	i, err := strconv.Atoi(source)
+	if err != nil {
+		return fmt.Errorf("invalid edge key %q", text)
+	}
+	j, err := strconv.Atoi(target)
+	if err != nil {
+		return fmt.Errorf("invalid edge key %q", text)
+	}
+	k.Source, k.Target = i, j
+	return nil
+}
+
+// SetAttribute sets an optional edge property, allocating the map on first use.
+func (e *Edge) SetAttribute(key string, value interface{}) {
+	if e.Attributes == nil {
+		e.Attributes = make(map[string]interface{})
+	}
+	e.Attributes[key] = value
+}
+
+// Graph represents the network: nodes, edges, and (optionally) node groups.
+// Group membership, fitness and positions keep their typed fields; any other
+// per-node data goes in NodeAttributes, and NodeAttribute and
+// AllNodeAttributes read both through one interface.
+type Graph struct {
+	NumAgents int                `json:"num_agents"`
+	Directed  bool               `json:"directed"`
+	Edges     map[EdgeKey]*Edge  `json:"edges"`
+	Groups    map[int]int        `json:"groups,omitempty"`    // Optional: group membership for homophily.
+	Labels    []string           `json:"labels,omitempty"`    // Optional: external node labels, indexed by node id.
+	Fitness   []float64          `json:"fitness,omitempty"`   // Optional: node fitness for the fitness strategy.
+	Removed   map[int]bool       `json:"removed,omitempty"`   // Optional: nodes removed by the death process.
+	Positions map[int][2]float64 `json:"positions,omitempty"` // Optional: node coordinates for the geometric strategy.
+
+	NodeAttributes map[int]map[string]interface{} `json:"node_attributes,omitempty"` // Optional: arbitrary node metadata, by node id.
+
+	counter         *edgeCounter   // Set in count-only mode, where edges are counted instead of stored.
+	maxMultiplicity int            // Cap on an edge's weight when links repeat (0 means no cap).
+	capHits         int            // Links ignored because their edge was at maxMultiplicity.
+	weightFunc      func() float64 // Draws the FloatWeight each link adds (nil leaves it 0).
+}
+
+// edgeCounter tracks edge statistics without materializing Edge values. Each
+// distinct pair costs one uint64 set entry instead of a string key and an *Edge.
+type edgeCounter struct {
+	pairs       map[uint64]int // Pair -> weight.
+	outDegree   []int
+	inDegree    []int
+	totalWeight int
+}
+
+// CountStats summarizes a count-only run.
+type CountStats struct {
+	NumAgents     int     `json:"num_agents"`
+	Edges         int     `json:"edges"`
+	Density       float64 `json:"density"`
+	AverageDegree float64 `json:"average_degree"`
+	MaxInDegree   int     `json:"max_in_degree"`
+	MaxOutDegree  int     `json:"max_out_degree"`
+	TotalWeight   int     `json:"total_weight"`
+}
+
+// newGraph returns an empty graph, counting rather than storing edges when
+// opts requests a count-only run.
+func newGraph(numAgents int, opts *SimOptions) *Graph {
+	G := &Graph{
+		NumAgents: numAgents,
+		Directed:  opts == nil || !opts.Undirected,
+		Edges:     make(map[EdgeKey]*Edge),
+	}
+	if opts != nil {
+		G.maxMultiplicity = opts.MaxMultiplicity
+		G.weightFunc = opts.WeightFunc
+	}
+	if opts != nil && opts.CountOnly {
+		G.counter = &edgeCounter{
+			pairs:     make(map[uint64]int),
+			outDegree: make([]int, numAgents),
+			inDegree:  make([]int, numAgents),
+		}
+	}
+	if opts != nil && opts.Start != nil {
+		G.copyFrom(opts.Start)
+	}
+	return G
+}
+
+// copyFrom fills a freshly created g with the edges and node data of start,
+// which must have the same number of nodes and directedness.
+func (g *Graph) copyFrom(start *Graph) {
+	for key, edge := range start.Edges {
+		if c := g.counter; c != nil {
+			c.pairs[uint64(edge.Source)*uint64(g.NumAgents)+uint64(edge.Target)] = edge.Weight
+			c.outDegree[edge.Source]++
+			c.inDegree[edge.Target]++
+			c.totalWeight += edge.Weight
+			continue
+		}
+		copied := *edge
+		g.Edges[key] = &copied
+	}
+	if len(start.Groups) > 0 {
+		g.Groups = make(map[int]int, len(start.Groups))
+		for i, group := range start.Groups {
+			g.Groups[i] = group
+		}
+	}
+	g.Labels = start.Labels
+	g.Fitness = start.Fitness
+	g.Positions = start.Positions
+	g.NodeAttributes = start.NodeAttributes
+	for i := range start.Removed {
+		if g.Removed == nil {
+			g.Removed = make(map[int]bool)
+		}
+		g.Removed[i] = true
+	}
+}
+
+// CapHits returns how many links were ignored because their edge had already
+// reached the MaxMultiplicity cap.
+func (g *Graph) CapHits() int {
+	return g.capHits
+}
+
+// edgeKey returns the Edges map key for a link between i and j. Undirected
+// graphs store each pair once, under the smaller id first.
+func (g *Graph) edgeKey(i, j int) EdgeKey {
+	if !g.Directed && i > j {
+		i, j = j, i
+	}
+	return EdgeKey{i, j}
+}
+
+// NumEdges returns the number of distinct edges, including in count-only mode.
+func (g *Graph) NumEdges() int {
+	if g.counter != nil {
+		return len(g.counter.pairs)
+	}
+	return len(g.Edges)
+}
+
+// CountStats returns summary statistics for the graph. It works both for
+// count-only graphs and for graphs with stored edges.
+func (g *Graph) CountStats() CountStats {
+	stats := CountStats{NumAgents: g.NumAgents, Edges: g.NumEdges()}
+	inDegree, outDegree := make([]int, g.NumAgents), make([]int, g.NumAgents)
+	if g.counter != nil {
+		inDegree, outDegree = g.counter.inDegree, g.counter.outDegree
+		stats.TotalWeight = g.counter.totalWeight
+	} else {
+		for _, edge := range g.Edges {
+			outDegree[edge.Source]++
+			inDegree[edge.Target]++
+			stats.TotalWeight += edge.Weight
+		}
+	}
+	for i := 0; i < g.NumAgents; i++ {
+		if inDegree[i] > stats.MaxInDegree {
+			stats.MaxInDegree = inDegree[i]
+		}
+		if outDegree[i] > stats.MaxOutDegree {
+			stats.MaxOutDegree = outDegree[i]
+		}
+	}
+	if g.NumAgents > 1 {
+		stats.Density = float64(stats.Edges) / float64(g.NumAgents*(g.NumAgents-1))
+		if !g.Directed {
+			stats.Density *= 2
+		}
+	}
+	if g.NumAgents > 0 {
+		stats.AverageDegree = 2 * float64(stats.Edges) / float64(g.NumAgents)
+	}
+	return stats
+}
+
+// addEdge records a link from i to j. A new edge starts with weight 1 (0 when
+// edge weights are disabled); linking an existing pair increments its weight
+// unless it has reached the multiplicity cap, in which case the link is ignored.
+// In an undirected graph, linking j to i is the same as linking i to j.
+// It returns true if a new edge was created.
+func (g *Graph) addEdge(i, j int, edgeWeights bool) bool {
+	if !g.Directed && i > j {
+		i, j = j, i
+	}
+	if c := g.counter; c != nil {
+		pair := uint64(i)*uint64(g.NumAgents) + uint64(j)
+		if weight, exists := c.pairs[pair]; exists {
+			if edgeWeights {
+				if g.maxMultiplicity > 0 && weight >= g.maxMultiplicity {
+					g.capHits++
+					return false
+				}
+				c.pairs[pair]++
+				c.totalWeight++
+			}
+			return false
+		}
+		c.pairs[pair] = 1
+		c.outDegree[i]++
+		c.inDegree[j]++
+		if edgeWeights {
+			c.totalWeight++
+		}
+		return true
+	}
+	key := g.edgeKey(i, j)
+	if edge, exists := g.Edges[key]; exists {
+		if edgeWeights {
+			if g.maxMultiplicity > 0 && edge.Weight >= g.maxMultiplicity {
+				g.capHits++
+				return false
+			}
+			edge.Weight++
+			if g.weightFunc != nil {
+				edge.FloatWeight += g.weightFunc()
+			}
+		}
+		return false
+	}
+	weight := 0
+	if edgeWeights {
+		weight = 1
+	}
+	g.Edges[key] = &Edge{
+		Source: i,
+		Target: j,
+		Weight: weight,
+	}
+	if g.weightFunc != nil {
+		g.Edges[key].FloatWeight = g.weightFunc()
+	}
+	return true
+}
+
+// AddEdge adds an edge from source to target with the given weight and reports
+// whether it is new. If the edge already exists, weight is added to its weight
+// instead. In an undirected graph, source and target may come in either order.
+// Both must be node ids in [0, NumAgents). Unlike the links simulations make,
+// AddEdge ignores the multiplicity cap and draws no continuous weight.
+func (g *Graph) AddEdge(source, target, weight int) bool {
+	if !g.Directed && source > target {
+		source, target = target, source
+	}
+	if c := g.counter; c != nil {
+		pair := uint64(source)*uint64(g.NumAgents) + uint64(target)
+		_, exists := c.pairs[pair]
+		c.pairs[pair] += weight
+		c.totalWeight += weight
+		if !exists {
+			c.outDegree[source]++
+			c.inDegree[target]++
+		}
+		return !exists
+	}
+	key := EdgeKey{source, target}
+	if edge, exists := g.Edges[key]; exists {
+		edge.Weight += weight
+		return false
+	}
+	g.Edges[key] = &Edge{Source: source, Target: target, Weight: weight}
+	return true
+}
+
+// RemoveEdge deletes the edge from source to target and reports whether it
+// existed. In an undirected graph, source and target may come in either order.
+func (g *Graph) RemoveEdge(source, target int) bool {
+	if !g.Directed && source > target {
+		source, target = target, source
+	}
+	if c := g.counter; c != nil {
+		pair := uint64(source)*uint64(g.NumAgents) + uint64(target)
+		weight, exists := c.pairs[pair]
+		if !exists {
+			return false
+		}
+		// totalWeight only counts weights when edge weights are on, in which
+		// case it is positive while any pair remains.
+		if c.totalWeight > 0 {
+			c.totalWeight -= weight
+		}
+		delete(c.pairs, pair)
+		c.outDegree[source]--
+		c.inDegree[target]--
+		return true
+	}
+	key := EdgeKey{source, target}
+	if _, exists := g.Edges[key]; !exists {
+		return false
+	}
+	delete(g.Edges, key)
+	return true
+}
+
+// Symmetrize returns the undirected projection of g: each connected pair is kept
+// once, as an edge from the smaller to the larger node id, with the weights of
+// i->j and j->i summed. Groups and labels are shared with g.
+func (g *Graph) Symmetrize() *Graph {
+	undirected := &Graph{
+		NumAgents: g.NumAgents,
+		Directed:  false,
+		Edges:     make(map[EdgeKey]*Edge, len(g.Edges)),
+		Groups:    g.Groups,
+		Labels:    g.Labels,
+	}
+	for _, edge := range g.Edges {
+		i, j := edge.Source, edge.Target
+		if i > j {
+			i, j = j, i
+		}
+		key := EdgeKey{i, j}
+		if existing, exists := undirected.Edges[key]; exists {
+			existing.Weight += edge.Weight
+			existing.FloatWeight += edge.FloatWeight
+			continue
+		}
+		undirected.Edges[key] = &Edge{Source: i, Target: j, Weight: edge.Weight, FloatWeight: edge.FloatWeight, Attributes: edge.Attributes}
+	}
+	return undirected
+}
+
+// Subgraph returns the subgraph of g induced by nodes: those nodes, renumbered
+// 0..k-1 in the order given, and every edge of g between two of them, with its
+// weights and attributes. Groups, fitness, positions and removals carry over to
+// the new ids, and each node is labelled with its label in g (its original id
+// unless g has labels), so results can be traced back. Ids outside g and
+// repeats are skipped.
+func Subgraph(g *Graph, nodes []int) *Graph {
+	index := make(map[int]int, len(nodes))
+	var kept []int
+	for _, v := range nodes {
+		if _, seen := index[v]; seen || v < 0 || v >= g.NumAgents {
+			continue
+		}
+		index[v] = len(kept)
+		kept = append(kept, v)
+	}
+	sub := &Graph{
+		NumAgents: len(kept),
+		Directed:  g.Directed,
+		Edges:     make(map[EdgeKey]*Edge),
+		Labels:    make([]string, len(kept)),
+	}
+	for i, v := range kept {
+		sub.Labels[i] = g.Label(v)
+		if group, ok := g.Groups[v]; ok {
+			if sub.Groups == nil {
+				sub.Groups = make(map[int]int)
+			}
+			sub.Groups[i] = group
+		}
+		if v < len(g.Fitness) {
+			sub.Fitness = append(sub.Fitness, g.Fitness[v])
+		}
+		if pos, ok := g.Positions[v]; ok {
+			if sub.Positions == nil {
+				sub.Positions = make(map[int][2]float64)
+			}
+			sub.Positions[i] = pos
+		}
+		if attributes := g.NodeAttributes[v]; len(attributes) > 0 {
+			if sub.NodeAttributes == nil {
+				sub.NodeAttributes = make(map[int]map[string]interface{})
+			}
+			sub.NodeAttributes[i] = attributes
+		}
+		if g.Removed[v] {
+			if sub.Removed == nil {
+				sub.Removed = make(map[int]bool)
+			}
+			sub.Removed[i] = true
+		}
+	}
+	if len(sub.Fitness) != len(kept) {
+		sub.Fitness = nil
+	}
+	for _, edge := range g.Edges {
+		i, iOK := index[edge.Source]
+		j, jOK := index[edge.Target]
+		if !iOK || !jOK {
+			continue
+		}
+		copied := *edge
+		key := sub.edgeKey(i, j)
+		copied.Source, copied.Target = key.Source, key.Target
+		sub.Edges[key] = &copied
+	}
+	return sub
+}
+
+// LargestComponentSubgraph returns the subgraph induced by the largest weakly
+// connected component of g (see ConnectedComponents and Subgraph), with nodes
+// renumbered in id order.
+func LargestComponentSubgraph(g *Graph) *Graph {
+	components := ConnectedComponents(g)
+	if len(components) == 0 {
+		return Subgraph(g, nil)
+	}
+	return Subgraph(g, components[0])
+}
+
+// Label returns the external label of node i, defaulting to its integer id.
+func (g *Graph) Label(i int) string {
+	if i >= 0 && i < len(g.Labels) {
+		return g.Labels[i]
+	}
+	return strconv.Itoa(i)
+}
+
+// builtinNodeAttributes names the node attributes backed by Graph's typed
+// fields, in the order exporters write them.
+var builtinNodeAttributes = []string{"group", "fitness", "x", "y"}
+
+// builtinNodeAttribute reports whether name is one of builtinNodeAttributes.
+func builtinNodeAttribute(name string) bool {
+	for _, builtin := range builtinNodeAttributes {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// SetNodeAttribute sets an arbitrary property of node i, allocating the maps
+// on first use. Group membership, fitness and positions have their own fields
+// and take precedence over attributes of the same name.
+func (g *Graph) SetNodeAttribute(i int, key string, value interface{}) {
+	if g.NodeAttributes == nil {
+		g.NodeAttributes = make(map[int]map[string]interface{})
+	}
+	if g.NodeAttributes[i] == nil {
+		g.NodeAttributes[i] = make(map[string]interface{})
+	}
+	g.NodeAttributes[i][key] = value
+}
+
+// NodeAttribute returns a property of node i: "group" (int), "fitness"
+// (float64), "x" and "y" (float64 coordinates) from the typed fields, or any
+// key set with SetNodeAttribute.
+func (g *Graph) NodeAttribute(i int, key string) (interface{}, bool) {
+	switch key {
+	case "group":
+		if group, ok := g.Groups[i]; ok {
+			return group, true
+		}
+	case "fitness":
+		if i >= 0 && i < len(g.Fitness) {
+			return g.Fitness[i], true
+		}
+	case "x", "y":
+		if pos, ok := g.Positions[i]; ok {
+			if key == "x" {
+				return pos[0], true
+			}
+			return pos[1], true
+		}
+	}
+	value, ok := g.NodeAttributes[i][key]
+	return value, ok
+}
+
+// AllNodeAttributes returns every property of node i (see NodeAttribute) in a
+// new map, or nil if it has none.
+func (g *Graph) AllNodeAttributes(i int) map[string]interface{} {
+	var all map[string]interface{}
+	set := func(key string, value interface{}) {
+		if all == nil {
+			all = make(map[string]interface{})
+		}
+		all[key] = value
+	}
+	for key, value := range g.NodeAttributes[i] {
+		set(key, value)
+	}
+	for _, key := range builtinNodeAttributes {
+		if value, ok := g.NodeAttribute(i, key); ok {
+			set(key, value)
+		}
+	}
+	return all
+}
+
+// removeNodes kills each live node independently with probability rate and
+// deletes every edge touching a killed node. Removed node ids are never reused:
+// they stay in Graph.Removed so later analyses can tell them apart from nodes
+// that simply have no links. It returns the number of nodes removed.
+func (g *Graph) removeNodes(rate float64, edgeWeights bool, rng *rand.Rand) int {
+	if rate <= 0 {
+		return 0
+	}
+	if g.Removed == nil {
+		g.Removed = make(map[int]bool)
+	}
+	dead := make(map[int]bool)
+	for i := 0; i < g.NumAgents; i++ {
+		if !g.Removed[i] && rng.Float64() < rate {
+			dead[i] = true
+			g.Removed[i] = true
+		}
+	}
+	if len(dead) == 0 {
+		return 0
+	}
+	if c := g.counter; c != nil {
+		n := uint64(g.NumAgents)
+		for pair, weight := range c.pairs {
+			i, j := int(pair/n), int(pair%n)
+			if !dead[i] && !dead[j] {
+				continue
+			}
+			delete(c.pairs, pair)
+			c.outDegree[i]--
+			c.inDegree[j]--
+			if edgeWeights {
+				c.totalWeight -= weight
+			}
+		}
+		return len(dead)
+	}
+	for key, edge := range g.Edges {
+		if dead[edge.Source] || dead[edge.Target] {
+			delete(g.Edges, key)
+		}
+	}
+	return len(dead)
+}
+
+// LiveNodes returns the number of nodes that have not been removed by the death process.
+func (g *Graph) LiveNodes() int {
+	return g.NumAgents - len(g.Removed)
+}
+
+// neighborSets returns, for every node, the set of out-neighbors and the set of
+// neighbors ignoring direction.
+func (g *Graph) neighborSets() (out, undirected []map[int]bool) {
+	out = make([]map[int]bool, g.NumAgents)
+	undirected = make([]map[int]bool, g.NumAgents)
+	for i := 0; i < g.NumAgents; i++ {
+		out[i] = make(map[int]bool)
+		undirected[i] = make(map[int]bool)
+	}
+	for _, edge := range g.Edges {
+		if edge.Source == edge.Target {
+			continue
+		}
+		out[edge.Source][edge.Target] = true
+		if !g.Directed {
+			out[edge.Target][edge.Source] = true
+		}
+		undirected[edge.Source][edge.Target] = true
+		undirected[edge.Target][edge.Source] = true
+	}
+	return out, undirected
+}
+
+// degrees returns each node's total degree (in-degree plus out-degree).
+func (g *Graph) degrees() []int {
+	degree := make([]int, g.NumAgents)
+	for _, edge := range g.Edges {
+		degree[edge.Source]++
+		degree[edge.Target]++
+	}
+	return degree
+}
+
+// InDegree returns each node's number of incoming edges. In an undirected graph
+// every edge counts at both ends, so InDegree, OutDegree and degrees agree.
+func (g *Graph) InDegree() []int {
+	if !g.Directed {
+		return g.degrees()
+	}
+	degree := make([]int, g.NumAgents)
+	for _, edge := range g.Edges {
+		degree[edge.Target]++
+	}
+	return degree
+}
+
+// OutDegree returns each node's number of outgoing edges (see InDegree for
+// undirected graphs).
+func (g *Graph) OutDegree() []int {
+	if !g.Directed {
+		return g.degrees()
+	}
+	degree := make([]int, g.NumAgents)
+	for _, edge := range g.Edges {
+		degree[edge.Source]++
+	}
+	return degree
+}
+
+// outAdjacency returns each node's out-neighbors (all neighbors, in an
+// undirected graph) in ascending order, so that processes driven by a seeded
+// rng visit neighbors reproducibly.
+func (g *Graph) outAdjacency() [][]int {
+	adj := make([][]int, g.NumAgents)
+	for _, edge := range g.Edges {
+		adj[edge.Source] = append(adj[edge.Source], edge.Target)
+		if !g.Directed {
+			adj[edge.Target] = append(adj[edge.Target], edge.Source)
+		}
+	}
+	for i := range adj {
+		sort.Ints(adj[i])
+	}
+	return adj
+}
+
+// weightedNeighbor is an adjacency-list entry carrying the edge weight.
+type weightedNeighbor struct {
+	Node   int
+	Weight float64
+}
+
+// edgeStrength is the weight an edge contributes in weighted computations: its
+// continuous FloatWeight if it has one, otherwise its integer Weight.
+// Unweighted edges (weight 0) count as 1.
+func edgeStrength(edge *Edge) float64 {
+	if edge.FloatWeight > 0 {
+		return edge.FloatWeight
+	}
+	if edge.Weight <= 0 {
+		return 1
+	}
+	return float64(edge.Weight)
+}
+
+// inAdjacency returns each node's in-neighbors (all neighbors, in an undirected
+// graph) with edge strengths, sorted by node.
+func (g *Graph) inAdjacency() [][]weightedNeighbor {
+	adj := make([][]weightedNeighbor, g.NumAgents)
+	for _, edge := range g.Edges {
+		adj[edge.Target] = append(adj[edge.Target], weightedNeighbor{Node: edge.Source, Weight: edgeStrength(edge)})
+		if !g.Directed {
+			adj[edge.Source] = append(adj[edge.Source], weightedNeighbor{Node: edge.Target, Weight: edgeStrength(edge)})
+		}
+	}
+	for i := range adj {
+		sort.Slice(adj[i], func(a, b int) bool { return adj[i][a].Node < adj[i][b].Node })
+	}
+	return adj
+}
@@ -0,0 +1,89 @@
+package graph
+
+import "testing"
+
+// TestAddRemoveEdge checks AddEdge and RemoveEdge on directed and undirected
+// graphs: re-adding an edge adds to its weight, and removing it twice reports
+// false the second time.
+func TestAddRemoveEdge(t *testing.T) {
+	for _, directed := range []bool{true, false} {
+		g := &Graph{NumAgents: 3, Directed: directed, Edges: map[EdgeKey]*Edge{}}
+		if !g.AddEdge(2, 0, 2) {
+			t.Fatalf("directed=%v: adding 2->0: want true", directed)
+		}
+		if g.AddEdge(2, 0, 3) {
+			t.Errorf("directed=%v: re-adding 2->0: want false", directed)
+		}
+		key := g.edgeKey(2, 0)
+		if edge := g.Edges[key]; edge == nil || edge.Weight != 5 {
+			t.Fatalf("directed=%v: edge under %v is %+v, want weight 5", directed, key, edge)
+		}
+		// The reverse is a different edge only in a directed graph.
+		if got := g.AddEdge(0, 2, 1); got != directed {
+			t.Errorf("directed=%v: adding 0->2 returned %v", directed, got)
+		}
+		if !g.RemoveEdge(2, 0) {
+			t.Errorf("directed=%v: removing 2->0: want true", directed)
+		}
+		if g.RemoveEdge(2, 0) {
+			t.Errorf("directed=%v: removing 2->0 again: want false", directed)
+		}
+		want := 0
+		if directed {
+			want = 1
+		}
+		if len(g.Edges) != want {
+			t.Errorf("directed=%v: %d edges left, want %d", directed, len(g.Edges), want)
+		}
+		if g.RemoveEdge(1, 2) {
+			t.Errorf("directed=%v: removing an edge that never existed: want false", directed)
+		}
+	}
+}
+
+// TestSubgraph checks that Subgraph renumbers the chosen nodes in the order
+// given, keeps only the edges between them, and carries groups and labels
+// over to the new ids.
+func TestSubgraph(t *testing.T) {
+	g := testGraph(5, true, [2]int{0, 1}, [2]int{1, 3}, [2]int{3, 4}, [2]int{4, 1}, [2]int{2, 3})
+	g.Edges[EdgeKey{3, 4}].Weight = 6
+	g.Groups = map[int]int{0: 0, 1: 1, 2: 0, 3: 2, 4: 1}
+	g.Labels = []string{"a", "b", "c", "d", "e"}
+
+	// 9 is out of range and the second 1 is a repeat; both are skipped.
+	sub := Subgraph(g, []int{4, 1, 3, 9, 1})
+	if sub.NumAgents != 3 || !sub.Directed {
+		t.Fatalf("got %d nodes, directed=%v; want 3, directed", sub.NumAgents, sub.Directed)
+	}
+	wantLabels := []string{"e", "b", "d"}
+	wantGroups := []int{1, 1, 2}
+	for i := range wantLabels {
+		if sub.Label(i) != wantLabels[i] {
+			t.Errorf("node %d label %q, want %q", i, sub.Label(i), wantLabels[i])
+		}
+		if group, ok := sub.Groups[i]; !ok || group != wantGroups[i] {
+			t.Errorf("node %d group %d (present %v), want %d", i, group, ok, wantGroups[i])
+		}
+	}
+	// 1->3, 3->4 and 4->1 become 1->2, 2->0 and 0->1; 0->1 and 2->3 leave the set.
+	want := map[EdgeKey]int{{1, 2}: 1, {2, 0}: 6, {0, 1}: 1}
+	if len(sub.Edges) != len(want) {
+		t.Errorf("got %d edges, want %d", len(sub.Edges), len(want))
+	}
+	for key, weight := range want {
+		edge, ok := sub.Edges[key]
+		if !ok {
+			t.Errorf("edge %v missing", key)
+			continue
+		}
+		if edge.Source != key.Source || edge.Target != key.Target || edge.Weight != weight {
+			t.Errorf("edge under %v is %+v, want weight %d", key, edge, weight)
+		}
+	}
+
+	// Without labels in g, nodes are labelled with their original ids.
+	g.Labels = nil
+	if got := Subgraph(g, []int{3, 0}).Label(0); got != "3" {
+		t.Errorf("unlabelled node 3 relabelled %q, want \"3\"", got)
+	}
+}
@@ -0,0 +1,1319 @@
+package graph
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// degreeFile is the layout of degrees.json. The in/out histograms are only
+// written for directed graphs.
+type degreeFile struct {
+	Degree    map[int]int `json:"degree"`
+	InDegree  map[int]int `json:"in_degree,omitempty"`
+	OutDegree map[int]int `json:"out_degree,omitempty"`
+}
+
+// SaveDegreeDistribution writes the degree histograms of g to path as JSON.
+func SaveDegreeDistribution(g *Graph, path string) error {
+	output := degreeFile{Degree: DegreeDistribution(g)}
+	if g.Directed {
+		output.InDegree = degreeHistogram(g, g.InDegree())
+		output.OutDegree = degreeHistogram(g, g.OutDegree())
+	}
+	outputBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling degree distribution: %w", err)
+	}
+	return ioutil.WriteFile(path, outputBytes, 0644)
+}
+
+// graphBuilder assembles a Graph from an imported node/edge list whose node ids
+// may be arbitrary strings. Nodes are numbered in order of first appearance.
+type graphBuilder struct {
+	index          map[string]int
+	ids            []string
+	labels         map[int]string // Explicit labels; nodes without one are labelled by their imported id.
+	groups         map[int]int
+	nodeAttributes map[int]map[string]interface{}
+	edges          map[EdgeKey]*Edge
+	plainIDs       bool // True while every id seen equals its own index ("0", "1", ...).
+	directed       bool // False merges i->j and j->i into one edge.
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{index: make(map[string]int), labels: make(map[int]string), groups: make(map[int]int), edges: make(map[EdgeKey]*Edge), plainIDs: true, directed: true}
+}
+
+// node returns the integer id for an imported node id, creating it if needed.
+func (b *graphBuilder) node(id string) int {
+	if i, ok := b.index[id]; ok {
+		return i
+	}
+	i := len(b.ids)
+	b.index[id] = i
+	b.ids = append(b.ids, id)
+	if id != strconv.Itoa(i) {
+		b.plainIDs = false
+	}
+	return i
+}
+
+// edge adds an edge between imported node ids. Parallel edges are merged by summing weights.
+func (b *graphBuilder) edge(source, target string, weight int, floatWeight float64, attributes map[string]interface{}) {
+	i, j := b.node(source), b.node(target)
+	if !b.directed && i > j {
+		i, j = j, i
+	}
+	key := EdgeKey{i, j}
+	if existing, exists := b.edges[key]; exists {
+		existing.Weight += weight
+		existing.FloatWeight += floatWeight
+		return
+	}
+	b.edges[key] = &Edge{Source: i, Target: j, Weight: weight, FloatWeight: floatWeight, Attributes: attributes}
+}
+
+// graph returns the built Graph. Original ids are kept as labels unless they were
+// already 0..n-1 in order and no explicit labels were given.
+func (b *graphBuilder) graph() *Graph {
+	G := &Graph{NumAgents: len(b.ids), Directed: b.directed, Edges: b.edges}
+	if len(b.groups) > 0 {
+		G.Groups = b.groups
+	}
+	if len(b.nodeAttributes) > 0 {
+		G.NodeAttributes = b.nodeAttributes
+	}
+	if !b.plainIDs || len(b.labels) > 0 {
+		G.Labels = append([]string(nil), b.ids...)
+		for i, label := range b.labels {
+			G.Labels[i] = label
+		}
+	}
+	return G
+}
+
+// parseWeight converts an imported weight value to the integer weight used by Edge.
+func parseWeight(value string) (int, error) {
+	w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid weight %q", value)
+	}
+	return int(math.Round(w)), nil
+}
+
+// graphMLDocument mirrors the parts of a GraphML file that the importer reads.
+type graphMLDocument struct {
+	Keys []struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	} `xml:"key"`
+	Graph struct {
+		EdgeDefault string `xml:"edgedefault,attr"`
+		Nodes       []struct {
+			ID   string        `xml:"id,attr"`
+			Data []graphMLData `xml:"data"`
+		} `xml:"node"`
+		Edges []struct {
+			Source string        `xml:"source,attr"`
+			Target string        `xml:"target,attr"`
+			Data   []graphMLData `xml:"data"`
+		} `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// typedAttribute converts a GraphML data value according to its declared attr.type.
+func typedAttribute(value, attrType string) interface{} {
+	value = strings.TrimSpace(value)
+	switch attrType {
+	case "int", "long":
+		if v, err := strconv.Atoi(value); err == nil {
+			return v
+		}
+	case "float", "double":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// readGraphML parses a GraphML document. The node attribute "group" becomes
+// Graph.Groups and other node attributes are kept in Graph.NodeAttributes; the
+// edge attributes "weight" and "float_weight" become
+// Edge.Weight and Edge.FloatWeight, and any other edge attributes are kept in
+// Edge.Attributes.
+func readGraphML(r io.Reader) (*Graph, error) {
+	var doc graphMLDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing GraphML: %w", err)
+	}
+	names := make(map[string]string)
+	types := make(map[string]string)
+	for _, key := range doc.Keys {
+		name := key.Name
+		if name == "" {
+			name = key.ID
+		}
+		names[key.ID] = name
+		types[key.ID] = key.Type
+	}
+	b := newGraphBuilder()
+	b.directed = doc.Graph.EdgeDefault != "undirected"
+	for _, node := range doc.Graph.Nodes {
+		i := b.node(node.ID)
+		for _, d := range node.Data {
+			if names[d.Key] == "group" {
+				group, err := strconv.Atoi(strings.TrimSpace(d.Value))
+				if err != nil {
+					return nil, fmt.Errorf("node %s: invalid group %q", node.ID, d.Value)
+				}
+				b.groups[i] = group
+				continue
+			}
+			if b.nodeAttributes == nil {
+				b.nodeAttributes = make(map[int]map[string]interface{})
+			}
+			if b.nodeAttributes[i] == nil {
+				b.nodeAttributes[i] = make(map[string]interface{})
+			}
+			b.nodeAttributes[i][names[d.Key]] = typedAttribute(d.Value, types[d.Key])
+		}
+	}
+	for _, edge := range doc.Graph.Edges {
+		weight, floatWeight := 0, 0.0
+		var attributes map[string]interface{}
+		for _, d := range edge.Data {
+			if names[d.Key] == "weight" {
+				w, err := parseWeight(d.Value)
+				if err != nil {
+					return nil, fmt.Errorf("edge %s->%s: %w", edge.Source, edge.Target, err)
+				}
+				weight = w
+				continue
+			}
+			if names[d.Key] == "float_weight" {
+				w, err := strconv.ParseFloat(strings.TrimSpace(d.Value), 64)
+				if err != nil {
+					return nil, fmt.Errorf("edge %s->%s: invalid float_weight %q", edge.Source, edge.Target, d.Value)
+				}
+				floatWeight = w
+				continue
+			}
+			if attributes == nil {
+				attributes = make(map[string]interface{})
+			}
+			attributes[names[d.Key]] = typedAttribute(d.Value, types[d.Key])
+		}
+		b.edge(edge.Source, edge.Target, weight, floatWeight, attributes)
+	}
+	return b.graph(), nil
+}
+
+// gmlList is a parsed GML list: key/value pairs in file order, where a value
+// is either a string/number token or a nested *gmlList.
+type gmlList struct {
+	keys   []string
+	values []interface{}
+}
+
+// get returns the first scalar value for key as a string.
+func (l *gmlList) get(key string) (string, bool) {
+	for i, k := range l.keys {
+		if s, ok := l.values[i].(string); ok && k == key {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// gmlTokens splits GML source into tokens, keeping quoted strings whole
+// (without the quotes) and dropping comment lines starting with '#'.
+func gmlTokens(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in GML")
+			}
+			// Quotes and non-ASCII characters inside strings are written as HTML entities.
+			tokens = append(tokens, "\""+html.UnescapeString(src[i+1:i+1+end]))
+			i += end + 2
+		default:
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t\n\r[]\"", rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, src[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+// parseGMLList parses "key value" pairs from tokens until a closing ']' or the end.
+func parseGMLList(tokens []string, pos int) (*gmlList, int, error) {
+	list := &gmlList{}
+	for pos < len(tokens) {
+		if tokens[pos] == "]" {
+			return list, pos + 1, nil
+		}
+		key := tokens[pos]
+		if pos+1 >= len(tokens) {
+			return nil, pos, fmt.Errorf("GML key %q has no value", key)
+		}
+		if tokens[pos+1] == "[" {
+			child, next, err := parseGMLList(tokens, pos+2)
+			if err != nil {
+				return nil, next, err
+			}
+			list.keys = append(list.keys, key)
+			list.values = append(list.values, child)
+			pos = next
+			continue
+		}
+		list.keys = append(list.keys, key)
+		list.values = append(list.values, strings.TrimPrefix(tokens[pos+1], "\""))
+		pos += 2
+	}
+	return list, pos, nil
+}
+
+// readGML parses a Graph Modelling Language document. Node "label" and "group"
+// values become Graph.Labels and Graph.Groups, edge "value" or "weight" becomes Edge.Weight,
+// "float_weight" becomes Edge.FloatWeight, and other scalar edge keys are kept in Edge.Attributes.
+func readGML(r io.Reader) (*Graph, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := gmlTokens(string(src))
+	if err != nil {
+		return nil, err
+	}
+	root, _, err := parseGMLList(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+	var graph *gmlList
+	for i, k := range root.keys {
+		if l, ok := root.values[i].(*gmlList); ok && k == "graph" {
+			graph = l
+			break
+		}
+	}
+	if graph == nil {
+		return nil, fmt.Errorf("GML has no graph block")
+	}
+	b := newGraphBuilder()
+	// GML defaults to undirected, but files from this tool have always been
+	// directed, so only an explicit "directed 0" makes the import undirected.
+	if directed, ok := graph.get("directed"); ok && directed == "0" {
+		b.directed = false
+	}
+	for i, k := range graph.keys {
+		item, ok := graph.values[i].(*gmlList)
+		if !ok || k != "node" {
+			continue
+		}
+		id, ok := item.get("id")
+		if !ok {
+			return nil, fmt.Errorf("GML node without id")
+		}
+		n := b.node(id)
+		if label, ok := item.get("label"); ok {
+			b.labels[n] = label
+		}
+		if group, ok := item.get("group"); ok {
+			g, err := strconv.Atoi(group)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: invalid group %q", id, group)
+			}
+			b.groups[n] = g
+		}
+	}
+	for i, k := range graph.keys {
+		item, ok := graph.values[i].(*gmlList)
+		if !ok || k != "edge" {
+			continue
+		}
+		source, okS := item.get("source")
+		target, okT := item.get("target")
+		if !okS || !okT {
+			return nil, fmt.Errorf("GML edge without source or target")
+		}
+		weight, floatWeight := 0, 0.0
+		var attributes map[string]interface{}
+		for j, key := range item.keys {
+			value, ok := item.values[j].(string)
+			if !ok || key == "source" || key == "target" {
+				continue
+			}
+			if key == "value" || key == "weight" {
+				if weight, err = parseWeight(value); err != nil {
+					return nil, fmt.Errorf("edge %s->%s: %w", source, target, err)
+				}
+				continue
+			}
+			if key == "float_weight" {
+				if floatWeight, err = strconv.ParseFloat(value, 64); err != nil {
+					return nil, fmt.Errorf("edge %s->%s: invalid float_weight %q", source, target, value)
+				}
+				continue
+			}
+			if attributes == nil {
+				attributes = make(map[string]interface{})
+			}
+			attributes[key] = value
+		}
+		b.edge(source, target, weight, floatWeight, attributes)
+	}
+	return b.graph(), nil
+}
+
+// networkFile is the on-disk layout of network.json: the graph with its edges
+// flattened into a list.
+type networkFile struct {
+	NumAgents int                `json:"num_agents"`
+	Directed  bool               `json:"directed"`
+	Edges     []Edge             `json:"edges"`
+	Groups    map[int]int        `json:"groups,omitempty"`
+	Labels    []string           `json:"labels,omitempty"`
+	Fitness   []float64          `json:"fitness,omitempty"`
+	Removed   []int              `json:"removed,omitempty"` // Ids of nodes removed by the death process, ascending.
+	Positions map[int][2]float64 `json:"positions,omitempty"`
+
+	NodeAttributes map[int]map[string]interface{} `json:"node_attributes,omitempty"`
+}
+
+// readNetworkJSON loads a network.json file written by SaveNetwork.
+func readNetworkJSON(r io.Reader) (*Graph, error) {
+	saved := networkFile{Directed: true} // Files written before the field existed are directed.
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("parsing network JSON: %w", err)
+	}
+	G := &Graph{
+		NumAgents: saved.NumAgents,
+		Directed:  saved.Directed,
+		Edges:     make(map[EdgeKey]*Edge, len(saved.Edges)),
+		Groups:    saved.Groups,
+		Labels:    saved.Labels,
+		Fitness:   saved.Fitness,
+		Positions: saved.Positions,
+
+		NodeAttributes: saved.NodeAttributes,
+	}
+	for i := range saved.Edges {
+		edge := saved.Edges[i]
+		if edge.Source < 0 || edge.Source >= G.NumAgents || edge.Target < 0 || edge.Target >= G.NumAgents {
+			return nil, fmt.Errorf("edge %d->%d references a node outside 0..%d", edge.Source, edge.Target, G.NumAgents-1)
+		}
+		if !G.Directed && edge.Source > edge.Target {
+			edge.Source, edge.Target = edge.Target, edge.Source
+		}
+		G.Edges[G.edgeKey(edge.Source, edge.Target)] = &edge
+	}
+	for _, i := range saved.Removed {
+		if G.Removed == nil {
+			G.Removed = make(map[int]bool)
+		}
+		G.Removed[i] = true
+	}
+	return G, nil
+}
+
+// jsonlHeader is the first line of a network.jsonl file; every following line
+// is one Edge.
+type jsonlHeader struct {
+	NumAgents int         `json:"num_agents"`
+	Directed  bool        `json:"directed"`
+	Groups    map[int]int `json:"groups,omitempty"`
+	Labels    []string    `json:"labels,omitempty"`
+}
+
+// writeJSONL streams g as JSON lines: a header with the node data, then one
+// edge object per line, encoded straight from the edge map so no second copy of
+// the edges is ever held in memory. Edge order is unspecified.
+func writeJSONL(g *Graph, w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	if err := enc.Encode(jsonlHeader{NumAgents: g.NumAgents, Directed: g.Directed, Groups: g.Groups, Labels: g.Labels}); err != nil {
+		return err
+	}
+	for _, edge := range g.Edges {
+		if err := enc.Encode(edge); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// readNetworkJSONL loads a network.jsonl file written by writeJSONL.
+func readNetworkJSONL(r io.Reader) (*Graph, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	header := jsonlHeader{Directed: true}
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("parsing JSON lines header: %w", err)
+	}
+	G := &Graph{
+		NumAgents: header.NumAgents,
+		Directed:  header.Directed,
+		Edges:     make(map[EdgeKey]*Edge),
+		Groups:    header.Groups,
+		Labels:    header.Labels,
+	}
+	for dec.More() {
+		edge := &Edge{}
+		if err := dec.Decode(edge); err != nil {
+			return nil, fmt.Errorf("parsing JSON lines edge: %w", err)
+		}
+		if edge.Source < 0 || edge.Source >= G.NumAgents || edge.Target < 0 || edge.Target >= G.NumAgents {
+			return nil, fmt.Errorf("edge %d->%d references a node outside 0..%d", edge.Source, edge.Target, G.NumAgents-1)
+		}
+		if !G.Directed && edge.Source > edge.Target {
+			edge.Source, edge.Target = edge.Target, edge.Source
+		}
+		G.Edges[G.edgeKey(edge.Source, edge.Target)] = edge
+	}
+	return G, nil
+}
+
+// gzipSuffix ends the names of gzip-compressed files.
+const gzipSuffix = ".gz"
+
+// compressedFile is an output file written through gzip.
+type compressedFile struct {
+	*gzip.Writer
+	file *os.File
+}
+
+// Close flushes the gzip stream and closes the file. The file is truncated
+// unless Close succeeds.
+func (f *compressedFile) Close() error {
+	err := f.Writer.Close()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// createFile creates path for writing, gzip-compressing what is written when
+// the name ends in ".gz".
+func createFile(path string) (io.WriteCloser, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, gzipSuffix) {
+		return file, nil
+	}
+	return &compressedFile{Writer: gzip.NewWriter(file), file: file}, nil
+}
+
+// writeFile writes data to path like ioutil.WriteFile, compressing it when the
+// name ends in ".gz".
+func writeFile(path string, data []byte) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// inputFile reads an opened file, through gzip if it is compressed.
+type inputFile struct {
+	io.Reader
+	file *os.File
+}
+
+func (f *inputFile) Close() error {
+	return f.file.Close()
+}
+
+// openFile opens path for reading, transparently decompressing gzip data. Gzip
+// is recognized by its magic bytes, so the name doesn't need to end in ".gz".
+func openFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(file)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("decompressing %s: %w", path, err)
+		}
+		return &inputFile{Reader: decompressed, file: file}, nil
+	}
+	return &inputFile{Reader: buffered, file: file}, nil
+}
+
+// ReadFile reads the whole file at path like ioutil.ReadFile, decompressing it
+// if it is gzip-compressed.
+func ReadFile(path string) ([]byte, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// ReadNetwork loads a network from path, choosing the format by file extension:
+// .graphml, .gml, .jsonl, or JSON (network.json layout) for anything else. A
+// gzip-compressed file is decompressed first, and a ".gz" after the extension
+// is ignored, so network.json.gz reads like network.json.
+func ReadNetwork(path string) (*Graph, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(path, gzipSuffix))) {
+	case ".graphml":
+		return readGraphML(file)
+	case ".gml":
+		return readGML(file)
+	case ".jsonl":
+		return readNetworkJSONL(file)
+	default:
+		return readNetworkJSON(file)
+	}
+}
+
+// StartNetwork loads the network config.SeedNetwork names, for use as
+// SimOptions.Start, and checks that it matches the config's node count and
+// directedness. It returns nil if no seed network is configured.
+func StartNetwork(config *Config) (*Graph, error) {
+	if config.SeedNetwork == "" {
+		return nil, nil
+	}
+	start, err := ReadNetwork(config.SeedNetwork)
+	if err != nil {
+		return nil, err
+	}
+	if start.NumAgents != config.NumAgents {
+		return nil, fmt.Errorf("%s has %d nodes but num_agents is %d", config.SeedNetwork, start.NumAgents, config.NumAgents)
+	}
+	if start.Directed != config.Directed {
+		return nil, fmt.Errorf("%s has directed=%t but the config has directed=%t", config.SeedNetwork, start.Directed, config.Directed)
+	}
+	for key, edge := range start.Edges {
+		if key != start.edgeKey(edge.Source, edge.Target) {
+			return nil, fmt.Errorf("%s: edge key %d_%d does not match edge %d->%d", config.SeedNetwork, key.Source, key.Target, edge.Source, edge.Target)
+		}
+	}
+	return start, nil
+}
+
+// SaveCentrality writes centrality scores to path as JSON, one object per
+// measure mapping node id to score.
+func SaveCentrality(path string, measures map[string]map[int]float64) error {
+	outputBytes, err := json.MarshalIndent(measures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling centrality: %w", err)
+	}
+	return ioutil.WriteFile(path, outputBytes, 0644)
+}
+
+// SaveMetrics writes the named summary metrics of g (all of them when names is
+// empty) to path as a JSON object. See SummaryMetrics.
+func SaveMetrics(g *Graph, path string, names []string) error {
+	outputBytes, err := json.MarshalIndent(SummaryMetrics(g, names), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling metrics: %w", err)
+	}
+	return ioutil.WriteFile(path, outputBytes, 0644)
+}
+
+// SaveNetwork writes the graph to path as JSON with the edges flattened into a
+// list, sorted by source and target so a seeded run always writes the same file.
+// A path ending in ".gz" is written gzip-compressed.
+func SaveNetwork(graph *Graph, path string) error {
+	edgesList := make([]Edge, 0, len(graph.Edges))
+	for _, edge := range sortedEdges(graph) {
+		edgesList = append(edgesList, *edge)
+	}
+	output := networkFile{
+		NumAgents: graph.NumAgents,
+		Directed:  graph.Directed,
+		Edges:     edgesList,
+		Groups:    graph.Groups,
+		Labels:    graph.Labels,
+		Fitness:   graph.Fitness,
+		Positions: graph.Positions,
+
+		NodeAttributes: graph.NodeAttributes,
+	}
+	for i := range graph.Removed {
+		output.Removed = append(output.Removed, i)
+	}
+	sort.Ints(output.Removed)
+	outputBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling graph: %w", err)
+	}
+	return writeFile(path, outputBytes)
+}
+
+// xmlEscape returns s with XML special characters escaped, for attribute values and text.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// graphMLType returns the GraphML attr.type for an edge attribute value.
+func graphMLType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case int:
+		return "int"
+	case float64:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// nodeAttributeColumns lists the node attributes present in g, with the type
+// typeOf gives each: the built-in ones (group, fitness, x, y) first, then the
+// others sorted by name.
+func nodeAttributeColumns(g *Graph, typeOf func(interface{}) string) ([]string, map[string]string) {
+	types := make(map[string]string)
+	for i := 0; i < g.NumAgents; i++ {
+		for name, value := range g.AllNodeAttributes(i) {
+			types[name] = typeOf(value)
+		}
+	}
+	var names, others []string
+	for _, name := range builtinNodeAttributes {
+		if _, ok := types[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for name := range types {
+		if !builtinNodeAttribute(name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...), types
+}
+
+// writeGraphML writes g as a GraphML document readable by Gephi, igraph and
+// readGraphML. Node ids are the node labels, edge weights are stored under the
+// "weight" key (and continuous weights, if any, under "float_weight"), and node
+// attributes (group membership, fitness, x and y coordinates and any others)
+// and edge attributes under keys named after them.
+func writeGraphML(g *Graph, w io.Writer) error {
+	attrTypes := make(map[string]string)
+	for _, edge := range g.Edges {
+		for name, value := range edge.Attributes {
+			attrTypes[name] = graphMLType(value)
+		}
+	}
+	attrNames := make([]string, 0, len(attrTypes))
+	for name := range attrTypes {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+
+	edgeDefault := "directed"
+	if !g.Directed {
+		edgeDefault = "undirected"
+	}
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	floatWeights := hasFloatWeights(g)
+	b.WriteString(`  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>` + "\n")
+	if floatWeights {
+		b.WriteString(`  <key id="float_weight" for="edge" attr.name="float_weight" attr.type="double"/>` + "\n")
+	}
+	nodeNames, nodeTypes := nodeAttributeColumns(g, graphMLType)
+	nodeKeys := make([]string, len(nodeNames))
+	for k, name := range nodeNames {
+		nodeKeys[k] = name
+		if !builtinNodeAttribute(name) {
+			nodeKeys[k] = fmt.Sprintf("n%d", k)
+		}
+		fmt.Fprintf(&b, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", nodeKeys[k], xmlEscape(name), nodeTypes[name])
+	}
+	for k, name := range attrNames {
+		fmt.Fprintf(&b, "  <key id=\"a%d\" for=\"edge\" attr.name=\"%s\" attr.type=\"%s\"/>\n", k, xmlEscape(name), attrTypes[name])
+	}
+	fmt.Fprintf(&b, "  <graph id=\"G\" edgedefault=\"%s\">\n", edgeDefault)
+	for i := 0; i < g.NumAgents; i++ {
+		attributes := g.AllNodeAttributes(i)
+		if len(attributes) == 0 {
+			fmt.Fprintf(&b, "    <node id=\"%s\"/>\n", xmlEscape(g.Label(i)))
+			continue
+		}
+		fmt.Fprintf(&b, "    <node id=\"%s\">\n", xmlEscape(g.Label(i)))
+		for k, name := range nodeNames {
+			if value, ok := attributes[name]; ok {
+				fmt.Fprintf(&b, "      <data key=\"%s\">%s</data>\n", nodeKeys[k], xmlEscape(fmt.Sprint(value)))
+			}
+		}
+		b.WriteString("    </node>\n")
+	}
+	for _, edge := range sortedEdges(g) {
+		fmt.Fprintf(&b, "    <edge source=\"%s\" target=\"%s\">\n", xmlEscape(g.Label(edge.Source)), xmlEscape(g.Label(edge.Target)))
+		fmt.Fprintf(&b, "      <data key=\"weight\">%d</data>\n", edge.Weight)
+		if floatWeights {
+			fmt.Fprintf(&b, "      <data key=\"float_weight\">%s</data>\n", strconv.FormatFloat(edge.FloatWeight, 'g', -1, 64))
+		}
+		for k, name := range attrNames {
+			if value, ok := edge.Attributes[name]; ok {
+				fmt.Fprintf(&b, "      <data key=\"a%d\">%s</data>\n", k, xmlEscape(fmt.Sprint(value)))
+			}
+		}
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeAdjacencyCSV writes g as an N×N adjacency matrix with node labels (ids
+// unless g has labels) as the header row and column. Entry (i, j) is the weight of edge i->j (its
+// continuous weight when the graph has them), or 1 when edge weights are off,
+// and 0 where there is no edge; undirected graphs give a symmetric matrix. Rows are streamed, but the output still grows as N², so it
+// is only practical for small and medium networks.
+func writeAdjacencyCSV(g *Graph, w io.Writer) error {
+	floatWeights := hasFloatWeights(g)
+	rows := make([]map[int]string, g.NumAgents)
+	set := func(i, j int, weight string) {
+		if rows[i] == nil {
+			rows[i] = make(map[int]string)
+		}
+		rows[i][j] = weight
+	}
+	for _, edge := range g.Edges {
+		weight := strconv.Itoa(edge.Weight)
+		if floatWeights {
+			weight = strconv.FormatFloat(edge.FloatWeight, 'g', -1, 64)
+		} else if edge.Weight <= 0 {
+			weight = "1"
+		}
+		set(edge.Source, edge.Target, weight)
+		if !g.Directed {
+			set(edge.Target, edge.Source, weight)
+		}
+	}
+	cw := csv.NewWriter(w)
+	record := make([]string, g.NumAgents+1)
+	for j := 0; j < g.NumAgents; j++ {
+		record[j+1] = g.Label(j)
+	}
+	cw.Write(record)
+	for i := 0; i < g.NumAgents; i++ {
+		record[0] = g.Label(i)
+		for j := 0; j < g.NumAgents; j++ {
+			if weight, ok := rows[i][j]; ok {
+				record[j+1] = weight
+			} else {
+				record[j+1] = "0"
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// adjacencyEntry is one neighbor in adjacency.json when the graph is weighted.
+// Node is the neighbor's id, or its label when the graph has labels.
+type adjacencyEntry struct {
+	Node   interface{} `json:"node"`
+	Weight interface{} `json:"weight"`
+}
+
+// writeAdjacencyList writes g as a JSON object mapping every node's label (its
+// id unless g has labels) to its sorted out-neighbors (all neighbors for
+// undirected graphs), one node per line in id order so the file diffs cleanly.
+// Nodes without edges get an empty list, so the keys give the full node set.
+// Unweighted graphs list bare neighbor ids, or labels; weighted ones list
+// {"node", "weight"} objects, using the continuous weight when the graph has
+// them.
+func writeAdjacencyList(g *Graph, w io.Writer) error {
+	floatWeights := hasFloatWeights(g)
+	weighted := floatWeights
+	// ref names a neighbor: by label when g has labels, otherwise by bare id.
+	ref := func(i int) interface{} {
+		if len(g.Labels) > 0 {
+			return g.Label(i)
+		}
+		return i
+	}
+	neighbors := make([][]*Edge, g.NumAgents)
+	for _, edge := range sortedEdges(g) {
+		weighted = weighted || edge.Weight > 0
+		neighbors[edge.Source] = append(neighbors[edge.Source], edge)
+		if !g.Directed && edge.Target != edge.Source {
+			neighbors[edge.Target] = append(neighbors[edge.Target], edge)
+		}
+	}
+	var b strings.Builder
+	b.WriteString("{")
+	for i, edges := range neighbors {
+		// Undirected edges were appended from both ends, so re-sort by neighbor.
+		other := func(edge *Edge) int {
+			if edge.Source == i {
+				return edge.Target
+			}
+			return edge.Source
+		}
+		sort.Slice(edges, func(a, c int) bool { return other(edges[a]) < other(edges[c]) })
+		var list interface{}
+		if weighted {
+			entries := make([]adjacencyEntry, len(edges))
+			for k, edge := range edges {
+				entries[k] = adjacencyEntry{Node: ref(other(edge)), Weight: edge.Weight}
+				if floatWeights {
+					entries[k].Weight = edge.FloatWeight
+				}
+			}
+			list = entries
+		} else {
+			refs := make([]interface{}, len(edges))
+			for k, edge := range edges {
+				refs[k] = ref(other(edge))
+			}
+			list = refs
+		}
+		data, err := json.Marshal(list)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(g.Label(i))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "\n  %s: %s", key, data)
+	}
+	b.WriteString("\n}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeEdgeListCSV writes g as a CSV edge list with the header
+// source,target,weight and one row per edge in sorted order, naming nodes by
+// label (their ids unless g has labels). The weight is 0
+// when edge weights are off. Graphs with continuous weights get a fourth
+// float_weight column.
+func writeEdgeListCSV(g *Graph, w io.Writer) error {
+	floatWeights := hasFloatWeights(g)
+	cw := csv.NewWriter(w)
+	header := []string{"source", "target", "weight"}
+	if floatWeights {
+		header = append(header, "float_weight")
+	}
+	cw.Write(header)
+	for _, edge := range sortedEdges(g) {
+		record := []string{g.Label(edge.Source), g.Label(edge.Target), strconv.Itoa(edge.Weight)}
+		if floatWeights {
+			record = append(record, strconv.FormatFloat(edge.FloatWeight, 'g', -1, 64))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writePajek writes g in Pajek .net format: a *Vertices section listing every
+// node with its label, then an *Arcs (directed) or *Edges (undirected) section
+// with one "source target weight" line per edge. Pajek numbers vertices from 1,
+// so node i is written as i+1. Weights are the continuous weights when the
+// graph has them, otherwise the integer weights, with 1 for unweighted edges.
+func writePajek(g *Graph, w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "*Vertices %d\n", g.NumAgents)
+	for i := 0; i < g.NumAgents; i++ {
+		// Pajek has no escape for quotes inside a label.
+		fmt.Fprintf(buf, "%d \"%s\"\n", i+1, strings.ReplaceAll(g.Label(i), `"`, "'"))
+	}
+	if g.Directed {
+		buf.WriteString("*Arcs\n")
+	} else {
+		buf.WriteString("*Edges\n")
+	}
+	floatWeights := hasFloatWeights(g)
+	for _, edge := range sortedEdges(g) {
+		weight := strconv.Itoa(edge.Weight)
+		if floatWeights {
+			weight = strconv.FormatFloat(edge.FloatWeight, 'g', -1, 64)
+		} else if edge.Weight <= 0 {
+			weight = "1"
+		}
+		fmt.Fprintf(buf, "%d %d %s\n", edge.Source+1, edge.Target+1, weight)
+	}
+	return buf.Flush()
+}
+
+// gmlString quotes s for GML. GML strings can't contain a double quote and are
+// ASCII, so '&', '"' and non-ASCII characters are written as HTML entities, the
+// convention NetworkX and igraph use (readGML decodes them).
+func gmlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '"':
+			b.WriteString("&quot;")
+		case r > 126 || r < 32:
+			fmt.Fprintf(&b, "&#%d;", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// gmlReal formats f as a GML real, which must contain a decimal point.
+func gmlReal(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// gmlValue formats an edge attribute value for GML: numbers as they are,
+// booleans as 1 or 0 and anything else as a string.
+func gmlValue(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return gmlReal(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return gmlString(fmt.Sprint(v))
+	}
+}
+
+// gmlKey reports whether name can be written as a GML key: a letter followed by
+// letters, digits or underscores.
+func gmlKey(name string) bool {
+	for i, r := range name {
+		letter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r != '_' && (r < '0' || r > '9')) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// writeGML writes g as a Graph Modelling Language document readable by igraph,
+// NetworkX, yEd and readGML. Nodes have their integer id, their label and, when
+// groups exist, a "group"; geometric positions go in a "graphics" block. Edges
+// carry their weight as "value" (omitted when edge weights are off), any
+// continuous weight as "float_weight", and extra attributes whose names are
+// valid GML keys under those names. "directed" follows the graph.
+func writeGML(g *Graph, w io.Writer) error {
+	directed := 0
+	if g.Directed {
+		directed = 1
+	}
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "graph [\n  directed %d\n", directed)
+	for i := 0; i < g.NumAgents; i++ {
+		fmt.Fprintf(buf, "  node [\n    id %d\n    label %s\n", i, gmlString(g.Label(i)))
+		if group, ok := g.Groups[i]; ok {
+			fmt.Fprintf(buf, "    group %d\n", group)
+		}
+		if pos, ok := g.Positions[i]; ok {
+			fmt.Fprintf(buf, "    graphics [\n      x %s\n      y %s\n    ]\n", gmlReal(pos[0]), gmlReal(pos[1]))
+		}
+		buf.WriteString("  ]\n")
+	}
+	for _, edge := range sortedEdges(g) {
+		fmt.Fprintf(buf, "  edge [\n    source %d\n    target %d\n", edge.Source, edge.Target)
+		if edge.Weight > 0 {
+			fmt.Fprintf(buf, "    value %d\n", edge.Weight)
+		}
+		if edge.FloatWeight != 0 {
+			fmt.Fprintf(buf, "    float_weight %s\n", gmlReal(edge.FloatWeight))
+		}
+		names := make([]string, 0, len(edge.Attributes))
+		for name := range edge.Attributes {
+			if gmlKey(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(buf, "    %s %s\n", name, gmlValue(edge.Attributes[name]))
+		}
+		buf.WriteString("  ]\n")
+	}
+	buf.WriteString("]\n")
+	return buf.Flush()
+}
+
+// gexfGroupColors are the node colors written per group to GEXF files, the
+// same default palette cmd/visualize uses. Groups beyond it wrap around.
+var gexfGroupColors = [][3]int{
+	{0x4e, 0x79, 0xa7}, {0xf2, 0x8e, 0x2b}, {0xe1, 0x57, 0x59}, {0x76, 0xb7, 0xb2}, {0x59, 0xa1, 0x4f},
+	{0xed, 0xc9, 0x48}, {0xb0, 0x7a, 0xa1}, {0xff, 0x9d, 0xa7}, {0x9c, 0x75, 0x5f}, {0xba, 0xb0, 0xac},
+}
+
+// gexfType maps a node or edge attribute value to its GEXF attribute type.
+func gexfType(value interface{}) string {
+	if t := graphMLType(value); t != "int" {
+		return t
+	}
+	return "integer"
+}
+
+// gexfPositionScale converts the unit-square positions of geometric networks
+// to GEXF viz coordinates.
+const gexfPositionScale = 1000.0
+
+// writeGEXF writes g as a GEXF 1.3 document, Gephi's native format. Node ids are
+// integer ids with the node labels as labels. Groups become a "group" node
+// attribute and a viz color per group, geometric positions become viz
+// positions, and fitness and other node and edge attributes become GEXF
+// attributes. Edge weights
+// are the continuous weights when the graph has them, otherwise the integer
+// weights, with 1 for unweighted edges.
+func writeGEXF(g *Graph, w io.Writer) error {
+	attrTypes := make(map[string]string)
+	for _, edge := range g.Edges {
+		for name, value := range edge.Attributes {
+			attrTypes[name] = gexfType(value)
+		}
+	}
+	attrNames := make([]string, 0, len(attrTypes))
+	for name := range attrTypes {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+
+	edgeType := "directed"
+	if !g.Directed {
+		edgeType = "undirected"
+	}
+	buf := bufio.NewWriter(w)
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<gexf xmlns="http://gexf.net/1.3" xmlns:viz="http://gexf.net/1.3/viz" version="1.3">` + "\n")
+	buf.WriteString("  <meta>\n    <creator>github.com/angrynarwhal/networks</creator>\n")
+	fmt.Fprintf(buf, "    <description>%d nodes, %d %s edges</description>\n  </meta>\n", g.NumAgents, len(g.Edges), edgeType)
+	fmt.Fprintf(buf, "  <graph mode=\"static\" defaultedgetype=\"%s\">\n", edgeType)
+	// Positions are written as viz positions rather than x and y attributes.
+	var nodeNames []string
+	columns, nodeTypes := nodeAttributeColumns(g, gexfType)
+	for _, name := range columns {
+		if len(g.Positions) == 0 || (name != "x" && name != "y") {
+			nodeNames = append(nodeNames, name)
+		}
+	}
+	nodeKeys := make([]string, len(nodeNames))
+	if len(nodeNames) > 0 {
+		buf.WriteString("    <attributes class=\"node\">\n")
+		for k, name := range nodeNames {
+			nodeKeys[k] = name
+			if !builtinNodeAttribute(name) {
+				nodeKeys[k] = fmt.Sprintf("n%d", k)
+			}
+			fmt.Fprintf(buf, "      <attribute id=\"%s\" title=\"%s\" type=\"%s\"/>\n", nodeKeys[k], xmlEscape(name), nodeTypes[name])
+		}
+		buf.WriteString("    </attributes>\n")
+	}
+	if len(attrNames) > 0 {
+		buf.WriteString("    <attributes class=\"edge\">\n")
+		for k, name := range attrNames {
+			fmt.Fprintf(buf, "      <attribute id=\"a%d\" title=\"%s\" type=\"%s\"/>\n", k, xmlEscape(name), attrTypes[name])
+		}
+		buf.WriteString("    </attributes>\n")
+	}
+	buf.WriteString("    <nodes>\n")
+	for i := 0; i < g.NumAgents; i++ {
+		group, hasGroup := g.Groups[i]
+		pos, hasPos := g.Positions[i]
+		attributes := g.AllNodeAttributes(i)
+		var values []string
+		for k, name := range nodeNames {
+			if value, ok := attributes[name]; ok {
+				values = append(values, fmt.Sprintf("          <attvalue for=\"%s\" value=\"%s\"/>\n", nodeKeys[k], xmlEscape(fmt.Sprint(value))))
+			}
+		}
+		if len(values) == 0 && !hasGroup && !hasPos {
+			fmt.Fprintf(buf, "      <node id=\"%d\" label=\"%s\"/>\n", i, xmlEscape(g.Label(i)))
+			continue
+		}
+		fmt.Fprintf(buf, "      <node id=\"%d\" label=\"%s\">\n", i, xmlEscape(g.Label(i)))
+		if len(values) > 0 {
+			buf.WriteString("        <attvalues>\n")
+			for _, v := range values {
+				buf.WriteString(v)
+			}
+			buf.WriteString("        </attvalues>\n")
+		}
+		if hasGroup {
+			n := len(gexfGroupColors)
+			c := gexfGroupColors[((group%n)+n)%n]
+			fmt.Fprintf(buf, "        <viz:color r=\"%d\" g=\"%d\" b=\"%d\"/>\n", c[0], c[1], c[2])
+		}
+		if hasPos {
+			fmt.Fprintf(buf, "        <viz:position x=\"%.3f\" y=\"%.3f\" z=\"0\"/>\n", pos[0]*gexfPositionScale, pos[1]*gexfPositionScale)
+		}
+		buf.WriteString("      </node>\n")
+	}
+	buf.WriteString("    </nodes>\n    <edges>\n")
+	floatWeights := hasFloatWeights(g)
+	for k, edge := range sortedEdges(g) {
+		weight := strconv.Itoa(edge.Weight)
+		if floatWeights {
+			weight = strconv.FormatFloat(edge.FloatWeight, 'g', -1, 64)
+		} else if edge.Weight <= 0 {
+			weight = "1"
+		}
+		fmt.Fprintf(buf, "      <edge id=\"%d\" source=\"%d\" target=\"%d\" weight=\"%s\"", k, edge.Source, edge.Target, weight)
+		var values []string
+		for a, name := range attrNames {
+			if value, ok := edge.Attributes[name]; ok {
+				values = append(values, fmt.Sprintf("          <attvalue for=\"a%d\" value=\"%s\"/>\n", a, xmlEscape(fmt.Sprint(value))))
+			}
+		}
+		if len(values) == 0 {
+			buf.WriteString("/>\n")
+			continue
+		}
+		buf.WriteString(">\n        <attvalues>\n")
+		for _, v := range values {
+			buf.WriteString(v)
+		}
+		buf.WriteString("        </attvalues>\n      </edge>\n")
+	}
+	buf.WriteString("    </edges>\n  </graph>\n</gexf>\n")
+	return buf.Flush()
+}
+
+// hasFloatWeights reports whether any edge of g has a continuous weight.
+func hasFloatWeights(g *Graph) bool {
+	for _, edge := range g.Edges {
+		if edge.FloatWeight != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedEdges returns the edges of g ordered by source, then target, so exported
+// files are stable from run to run.
+func sortedEdges(g *Graph) []*Edge {
+	edges := make([]*Edge, 0, len(g.Edges))
+	for _, edge := range g.Edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(a, b int) bool {
+		if edges[a].Source != edges[b].Source {
+			return edges[a].Source < edges[b].Source
+		}
+		return edges[a].Target < edges[b].Target
+	})
+	return edges
+}
+
+// exporter writes a graph in one output_format to its file.
+type exporter struct {
+	file  string
+	write func(g *Graph, w io.Writer) error
+}
+
+// exporters are the output formats written alongside network.json, keyed by
+// their output_format value.
+var exporters = map[string]exporter{
+	"graphml":        {file: "network.graphml", write: writeGraphML},
+	"adjacency_csv":  {file: "network_matrix.csv", write: writeAdjacencyCSV},
+	"jsonl":          {file: "network.jsonl", write: writeJSONL},
+	"pajek":          {file: "network.net", write: writePajek},
+	"edgelist_csv":   {file: "edges.csv", write: writeEdgeListCSV},
+	"gexf":           {file: "network.gexf", write: writeGEXF},
+	"gml":            {file: "network.gml", write: writeGML},
+	"adjacency_list": {file: "adjacency.json", write: writeAdjacencyList},
+}
+
+// ExportNetwork writes g in the given output_format and returns the file name.
+func ExportNetwork(g *Graph, format string) (string, error) {
+	return exportNetwork(g, format, exporters[format].file)
+}
+
+// ExportNetworkCompressed is ExportNetwork writing a gzip-compressed file whose
+// name has ".gz" added, e.g. network.jsonl.gz.
+func ExportNetworkCompressed(g *Graph, format string) (string, error) {
+	return exportNetwork(g, format, exporters[format].file+gzipSuffix)
+}
+
+// exportNetwork writes g in the given output_format to path.
+func exportNetwork(g *Graph, format, path string) (string, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := exporters[format].write(g, file); err != nil {
+		file.Close()
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestGMLRoundTrip writes a graph with writeGML and reads it back with
+// readGML: nodes, edges, weights, groups and labels that need escaping must
+// all survive.
+func TestGMLRoundTrip(t *testing.T) {
+	for _, directed := range []bool{true, false} {
+		g := &Graph{
+			NumAgents: 4,
+			Directed:  directed,
+			Edges:     map[EdgeKey]*Edge{},
+			Groups:    map[int]int{0: 0, 1: 1, 2: 1, 3: 2},
+			Labels:    []string{"plain", `say "hi"`, "Tom & Jerry", "Zoë ☃"},
+		}
+		g.AddEdge(0, 1, 3)
+		g.AddEdge(1, 2, 1)
+		g.AddEdge(2, 0, 7)
+		g.AddEdge(3, 3, 2)
+
+		var buf bytes.Buffer
+		if err := writeGML(g, &buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readGML(&buf)
+		if err != nil {
+			t.Fatalf("directed=%v: reading back: %v\n%s", directed, err, buf.String())
+		}
+		if got.NumAgents != g.NumAgents || got.Directed != directed {
+			t.Fatalf("directed=%v: got %d nodes, directed=%v", directed, got.NumAgents, got.Directed)
+		}
+		for i := 0; i < g.NumAgents; i++ {
+			if got.Label(i) != g.Label(i) {
+				t.Errorf("directed=%v: node %d label %q, want %q", directed, i, got.Label(i), g.Label(i))
+			}
+			if got.Groups[i] != g.Groups[i] {
+				t.Errorf("directed=%v: node %d group %d, want %d", directed, i, got.Groups[i], g.Groups[i])
+			}
+		}
+		if len(got.Edges) != len(g.Edges) {
+			t.Errorf("directed=%v: got %d edges, want %d", directed, len(got.Edges), len(g.Edges))
+		}
+		for key, edge := range g.Edges {
+			e, ok := got.Edges[key]
+			if !ok {
+				t.Errorf("directed=%v: edge %d->%d missing", directed, edge.Source, edge.Target)
+				continue
+			}
+			if e.Weight != edge.Weight {
+				t.Errorf("directed=%v: edge %d->%d weight %d, want %d", directed, edge.Source, edge.Target, e.Weight, edge.Weight)
+			}
+		}
+	}
+}
+
+// TestExportsUseLabels checks that the edge list, adjacency matrix and
+// adjacency list name nodes by label, and by bare id when there are none.
+func TestExportsUseLabels(t *testing.T) {
+	tests := []struct {
+		name            string
+		write           func(*Graph, io.Writer) error
+		labelled, plain string
+	}{
+		{"edgelist_csv", writeEdgeListCSV,
+			"source,target,weight\nann,bo,0\nbo,\"c,d\",0\n",
+			"source,target,weight\n0,1,0\n1,2,0\n"},
+		{"adjacency_csv", writeAdjacencyCSV,
+			",ann,bo,\"c,d\"\nann,0,1,0\nbo,0,0,1\n\"c,d\",0,0,0\n",
+			",0,1,2\n0,0,1,0\n1,0,0,1\n2,0,0,0\n"},
+		{"adjacency_list", writeAdjacencyList,
+			"{\n  \"ann\": [\"bo\"],\n  \"bo\": [\"c,d\"],\n  \"c,d\": []\n}\n",
+			"{\n  \"0\": [1],\n  \"1\": [2],\n  \"2\": []\n}\n"},
+	}
+	for _, tt := range tests {
+		// Unweighted, so the adjacency list holds bare neighbors.
+		g := &Graph{NumAgents: 3, Directed: true, Edges: map[EdgeKey]*Edge{}}
+		g.AddEdge(0, 1, 0)
+		g.AddEdge(1, 2, 0)
+		var buf bytes.Buffer
+		if err := tt.write(g, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.plain {
+			t.Errorf("%s without labels:\n%s\nwant:\n%s", tt.name, buf.String(), tt.plain)
+		}
+		g.Labels = []string{"ann", "bo", "c,d"}
+		buf.Reset()
+		if err := tt.write(g, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.labelled {
+			t.Errorf("%s with labels:\n%s\nwant:\n%s", tt.name, buf.String(), tt.labelled)
+		}
+	}
+}
+
+// TestReadNetworkJSONLUndirected checks that an undirected JSON lines file
+// listing an edge higher id first is read as the lower-first edge that
+// RemoveEdge and Diff look up.
+func TestReadNetworkJSONLUndirected(t *testing.T) {
+	src := `{"num_agents": 6, "directed": false}
+{"source": 5, "target": 2, "weight": 3}
+`
+	g, err := readNetworkJSONL(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edge := g.Edges[EdgeKey{2, 5}]
+	if edge == nil || edge.Source != 2 || edge.Target != 5 || edge.Weight != 3 {
+		t.Fatalf("edge under {2 5} is %+v, want 2-5 with weight 3", edge)
+	}
+	if added, removed, _ := Diff(g, testGraph(6, false, [2]int{2, 5})); len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Diff against the same edge: %d added, %d removed", len(added), len(removed))
+	}
+	if !g.RemoveEdge(5, 2) || len(g.Edges) != 0 {
+		t.Errorf("RemoveEdge(5, 2) left %d edges", len(g.Edges))
+	}
+}
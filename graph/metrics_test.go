@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testGraph builds a graph on n nodes with weight-1 edges between the given
+// pairs.
+func testGraph(n int, directed bool, pairs ...[2]int) *Graph {
+	g := &Graph{NumAgents: n, Directed: directed, Edges: map[EdgeKey]*Edge{}}
+	for _, p := range pairs {
+		g.AddEdge(p[0], p[1], 1)
+	}
+	return g
+}
+
+// TestCountTriangles checks a single triangle with a pendant node hanging off
+// it, which must count once whether the triangle's edges are stored one way,
+// reciprocated, or undirected.
+func TestCountTriangles(t *testing.T) {
+	tests := []struct {
+		name string
+		g    *Graph
+		want int
+	}{
+		{"undirected", testGraph(4, false, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}), 1},
+		{"directed", testGraph(4, true, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{3, 2}), 1},
+		{"reciprocal", testGraph(4, true, [2]int{0, 1}, [2]int{1, 0}, [2]int{1, 2}, [2]int{0, 2}, [2]int{2, 3}), 1},
+		{"path", testGraph(4, false, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}), 0},
+	}
+	for _, tt := range tests {
+		if got := CountTriangles(tt.g); got != tt.want {
+			t.Errorf("%s: CountTriangles = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestDiff compares hand-built before and after networks with an added, a
+// removed, a reweighted and an unchanged edge.
+func TestDiff(t *testing.T) {
+	before := testGraph(4, true, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3})
+	after := testGraph(4, true, [2]int{0, 1}, [2]int{1, 2}, [2]int{3, 0}, [2]int{1, 3})
+	after.Edges[EdgeKey{1, 2}].Weight = 4
+
+	added, removed, weightChanges := Diff(before, after)
+	if len(added) != 2 || added[0].Source != 1 || added[0].Target != 3 || added[1].Source != 3 || added[1].Target != 0 {
+		t.Errorf("added = %+v, want 1->3 and 3->0 in that order", added)
+	}
+	if len(removed) != 1 || removed[0].Source != 2 || removed[0].Target != 3 {
+		t.Errorf("removed = %+v, want 2->3", removed)
+	}
+	if len(weightChanges) != 1 || weightChanges[EdgeKey{1, 2}] != 3 {
+		t.Errorf("weightChanges = %v, want 1->2 up by 3", weightChanges)
+	}
+	if got, want := DiffSummary(added, removed, weightChanges), "2 edges added, 1 removed, 1 weight changed"; got != want {
+		t.Errorf("DiffSummary = %q, want %q", got, want)
+	}
+
+	// Reversing an edge in a directed graph removes one edge and adds another.
+	added, removed, _ = Diff(testGraph(2, true, [2]int{0, 1}), testGraph(2, true, [2]int{1, 0}))
+	if len(added) != 1 || len(removed) != 1 {
+		t.Errorf("reversed directed edge: %d added, %d removed; want 1 and 1", len(added), len(removed))
+	}
+	// An undirected graph has no direction to reverse.
+	added, removed, _ = Diff(testGraph(2, false, [2]int{0, 1}), testGraph(2, false, [2]int{1, 0}))
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("reversed undirected edge: %d added, %d removed; want none", len(added), len(removed))
+	}
+
+	added, removed, weightChanges = Diff(before, before)
+	if got, want := DiffSummary(added, removed, weightChanges), "0 edges added, 0 removed, 0 weights changed"; got != want {
+		t.Errorf("identical networks: DiffSummary = %q, want %q", got, want)
+	}
+}
+
+// TestDegreeEntropy checks that a regular graph, where every node has the same
+// degree, has entropy 0, and that preferential attachment's heterogeneous
+// degrees score clearly higher.
+func TestDegreeEntropy(t *testing.T) {
+	ring, err := RingLatticeSimulation(200, 4, false, &SimOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ring.DegreeEntropy(); got != 0 {
+		t.Errorf("ring lattice: DegreeEntropy = %v, want 0", got)
+	}
+	// Nodes removed by the death process aren't degree-0 nodes.
+	triangle := testGraph(5, false, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0})
+	triangle.Removed = map[int]bool{3: true, 4: true}
+	if got := triangle.DegreeEntropy(); got != 0 {
+		t.Errorf("triangle with removed nodes: DegreeEntropy = %v, want 0", got)
+	}
+	pa, err := PreferentialAttachmentSimulation(200, 1, 2, "complete_seed", 1, nil, false, &SimOptions{}, rand.New(rand.NewSource(211)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pa.DegreeEntropy(); got < 1 {
+		t.Errorf("preferential attachment: DegreeEntropy = %v, want at least 1 bit", got)
+	}
+}
+
+// TestEigenvectorCentralityConvergence checks that running out of iterations
+// is reported as an error alongside the last estimate.
+func TestEigenvectorCentralityConvergence(t *testing.T) {
+	g := testGraph(4, false, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}, [2]int{3, 0}, [2]int{0, 2})
+	scores, err := EigenvectorCentrality(g, 1)
+	if err == nil {
+		t.Error("one iteration: expected a convergence error")
+	}
+	if len(scores) != 4 {
+		t.Errorf("one iteration: got %d scores, want the estimate for all 4 nodes", len(scores))
+	}
+	scores, err = EigenvectorCentrality(g, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nodes 0 and 2 have the extra chord, so they outrank 1 and 3.
+	if scores[0] <= scores[1] || scores[2] <= scores[3] {
+		t.Errorf("scores %v: want nodes 0 and 2 above 1 and 3", scores)
+	}
+	if _, err := EigenvectorCentrality(testGraph(3, true), 1); err != nil {
+		t.Errorf("graph without edges: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// RunReport collects what a run produced so it can be summarized in report.md.
+type RunReport struct {
+	Config  *Config
+	Graph   *Graph
+	Metrics Metrics
+	Motifs  map[string]int // Motif counts, if motif counting ran.
+	Files   []string       // Files written by the run.
+}
+
+// reportTopNodes is how many of the most central nodes the report lists.
+const reportTopNodes = 10
+
+// WriteReport writes a Markdown summary of the run: the config, the computed
+// metrics, the most central nodes and links to the generated files.
+func WriteReport(path string, r *RunReport) error {
+	var b strings.Builder
+	G := r.Graph
+	fmt.Fprintf(&b, "# Network simulation report\n\n")
+
+	configJSON, err := json.MarshalIndent(r.Config, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "## Configuration\n\n```json\n%s\n```\n\n", configJSON)
+
+	fmt.Fprintf(&b, "## Metrics\n\n| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Nodes | %d |\n", r.Metrics.Nodes)
+	fmt.Fprintf(&b, "| Edges | %d |\n", r.Metrics.Edges)
+	stats := ComputeStats(G)
+	fmt.Fprintf(&b, "| Density | %.4f |\n", stats.Density)
+	fmt.Fprintf(&b, "| Average degree | %.3f |\n", stats.AverageDegree)
+	fmt.Fprintf(&b, "| Max degree | %d |\n", stats.MaxDegree)
+	fmt.Fprintf(&b, "| Isolated nodes | %d |\n", stats.IsolatedNodes)
+	fmt.Fprintf(&b, "| Degree entropy (bits) | %.4f |\n", r.Metrics.DegreeEntropy)
+	fmt.Fprintf(&b, "| Clustering coefficient | %.4f |\n", r.Metrics.ClusteringCoefficient)
+	fmt.Fprintf(&b, "| Connected components | %d |\n", r.Metrics.Components)
+	fmt.Fprintf(&b, "| Largest component (nodes) | %d |\n", r.Metrics.LargestComponent)
+	fmt.Fprintf(&b, "| Max core (k) | %d |\n", r.Metrics.MaxCore)
+	fmt.Fprintf(&b, "| Max-core size (nodes) | %d |\n\n", r.Metrics.MaxCoreSize)
+
+	if len(G.Groups) > 0 {
+		averages := G.DegreeByGroup(G.Groups)
+		sizes := make(map[int]int)
+		for _, group := range G.Groups {
+			sizes[group]++
+		}
+		groupIDs := make([]int, 0, len(averages))
+		for group := range averages {
+			groupIDs = append(groupIDs, group)
+		}
+		sort.Ints(groupIDs)
+		fmt.Fprintf(&b, "### Degree by group\n\n| Group | Nodes | Avg degree |\n|---|---|---|\n")
+		for _, group := range groupIDs {
+			fmt.Fprintf(&b, "| %d | %d | %.2f |\n", group, sizes[group], averages[group])
+		}
+		fmt.Fprintln(&b)
+	}
+
+	if len(r.Motifs) > 0 {
+		codes := make([]string, 0, len(r.Motifs))
+		for code := range r.Motifs {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Fprintf(&b, "### Motifs\n\n| Motif | Count |\n|---|---|\n")
+		for _, code := range codes {
+			fmt.Fprintf(&b, "| `%s` | %d |\n", code, r.Motifs[code])
+		}
+		fmt.Fprintln(&b)
+	}
+
+	degree, inDegree, outDegree := G.degrees(), G.InDegree(), G.OutDegree()
+	nodes := make([]int, G.NumAgents)
+	for i := range nodes {
+		nodes[i] = i
+	}
+	sort.SliceStable(nodes, func(a, b int) bool {
+		return degree[nodes[a]] > degree[nodes[b]]
+	})
+	if len(nodes) > reportTopNodes {
+		nodes = nodes[:reportTopNodes]
+	}
+	fmt.Fprintf(&b, "## Top central nodes (by degree)\n\n| Node | Degree | In | Out |\n|---|---|---|---|\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", G.Label(n), degree[n], inDegree[n], outDegree[n])
+	}
+	fmt.Fprintln(&b)
+
+	fmt.Fprintf(&b, "## Generated files\n\n")
+	for _, file := range r.Files {
+		fmt.Fprintf(&b, "- [%s](%s)\n", file, file)
+	}
+	// Images come from cmd/visualize, so only link them if they're already on disk.
+	for _, image := range []string{"network.png", "network.svg"} {
+		if _, err := os.Stat(image); err == nil {
+			fmt.Fprintf(&b, "\n![Network](%s)\n", image)
+		}
+	}
+	return ioutil.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,306 @@
+package graph
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configSchemaJSON is the JSON Schema of config.json. It describes the types,
+// ranges and allowed values of every setting and the settings each strategy
+// requires; cross-field rules (a partition summing to num_agents, say) stay in
+// LoadConfig.
+//
+//go:embed config.schema.json
+var configSchemaJSON []byte
+
+// ConfigSchema returns the JSON Schema config files are validated against, for
+// editors and other tools.
+func ConfigSchema() []byte {
+	return append([]byte(nil), configSchemaJSON...)
+}
+
+// schema is the subset of JSON Schema that config.schema.json uses.
+type schema struct {
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	AllOf                []*schema          `json:"allOf"`
+	If                   *schema            `json:"if"`
+	Then                 *schema            `json:"then"`
+}
+
+// configSchema is the parsed configSchemaJSON. The schema is part of the
+// source, so a broken one is a programming error.
+var configSchema = func() *schema {
+	var s schema
+	if err := json.Unmarshal(configSchemaJSON, &s); err != nil {
+		panic(fmt.Sprintf("config.schema.json: %v", err))
+	}
+	return &s
+}()
+
+// ValidateConfig checks a config file's contents against the embedded JSON
+// Schema before anything is decoded, so wrong types, out-of-range values,
+// unknown keys and settings a strategy requires are reported precisely, with
+// the path of the offending value (e.g. "pipeline[1].p_in must be between 0
+// and 1, got 1.5"). Every problem is reported, one per line, in a single error.
+func ValidateConfig(raw []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	var problems []string
+	configSchema.validate(value, "", "", &problems)
+	return problemsError(problems)
+}
+
+// problemsError turns a list of config problems into one error, or nil.
+func problemsError(problems []string) error {
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s", problems[0])
+	default:
+		return fmt.Errorf("%d invalid settings:\n  %s", len(problems), strings.Join(problems, "\n  "))
+	}
+}
+
+// validate appends a message for every way value breaks s. path names the
+// value ("" for the whole config), and condition explains why a then-branch
+// applies, e.g. ` when linking_strategy is "gnm"`.
+func (s *schema) validate(value interface{}, path, condition string, problems *[]string) {
+	name := path
+	if name == "" {
+		name = "config"
+	}
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, name+" "+fmt.Sprintf(format, args...))
+	}
+	if s.Type != "" && !hasType(value, s.Type) {
+		fail("must be %s, got %s", typeName(s.Type), describe(value))
+		return
+	}
+	if s.Enum != nil && !inEnum(value, s.Enum) {
+		fail("must be %s, got %s", choices(s.Enum), describe(value))
+		return
+	}
+	if s.Const != nil && !jsonEqual(value, s.Const) {
+		fail("must be %s, got %s", describe(s.Const), describe(value))
+		return
+	}
+	if n, ok := value.(float64); ok {
+		if msg := s.rangeProblem(n); msg != "" {
+			fail("%s, got %s", msg, describe(value))
+		}
+	}
+	if items, ok := value.([]interface{}); ok {
+		if msg := s.lengthProblem(len(items)); msg != "" {
+			fail("%s, got %d", msg, len(items))
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), "", problems)
+			}
+		}
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		for _, key := range s.Required {
+			if _, present := object[key]; !present {
+				*problems = append(*problems, fmt.Sprintf("%s is required%s", schemaPath(path, key), condition))
+			}
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, known := s.Properties[key]; known {
+				property.validate(object[key], schemaPath(path, key), "", problems)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				fail("has unknown setting %q", key)
+			}
+		}
+	}
+	for _, sub := range s.AllOf {
+		sub.validate(value, path, condition, problems)
+	}
+	if s.If != nil && s.Then != nil && s.If.matches(value) {
+		s.Then.validate(value, path, condition+s.If.describeCondition(), problems)
+	}
+}
+
+// matches reports whether value satisfies s, as an if-condition.
+func (s *schema) matches(value interface{}) bool {
+	var problems []string
+	s.validate(value, "", "", &problems)
+	return len(problems) == 0
+}
+
+// describeCondition phrases the const properties of an if-schema for error
+// messages.
+func (s *schema) describeCondition() string {
+	var parts []string
+	for key, property := range s.Properties {
+		if property.Const != nil {
+			parts = append(parts, fmt.Sprintf("%s is %s", key, describe(property.Const)))
+		}
+	}
+	sort.Strings(parts)
+	if len(parts) == 0 {
+		return ""
+	}
+	return " when " + strings.Join(parts, " and ")
+}
+
+// rangeProblem describes how n falls outside s's bounds, or returns "".
+func (s *schema) rangeProblem(n float64) string {
+	low, lowOK := "", true
+	if s.Minimum != nil {
+		low, lowOK = "at least "+formatNumber(*s.Minimum), n >= *s.Minimum
+	}
+	if s.ExclusiveMinimum != nil {
+		low, lowOK = "greater than "+formatNumber(*s.ExclusiveMinimum), n > *s.ExclusiveMinimum
+	}
+	high, highOK := "", true
+	if s.Maximum != nil {
+		high, highOK = "at most "+formatNumber(*s.Maximum), n <= *s.Maximum
+	}
+	if s.ExclusiveMaximum != nil {
+		high, highOK = "less than "+formatNumber(*s.ExclusiveMaximum), n < *s.ExclusiveMaximum
+	}
+	if lowOK && highOK {
+		return ""
+	}
+	switch {
+	case s.Minimum != nil && s.Maximum != nil:
+		return fmt.Sprintf("must be between %s and %s", formatNumber(*s.Minimum), formatNumber(*s.Maximum))
+	case low != "" && high != "":
+		return "must be " + low + " and " + high
+	case low != "":
+		return "must be " + low
+	default:
+		return "must be " + high
+	}
+}
+
+// lengthProblem describes how an array length breaks s's item counts, or
+// returns "".
+func (s *schema) lengthProblem(n int) string {
+	switch {
+	case s.MinItems != nil && s.MaxItems != nil && *s.MinItems == *s.MaxItems && n != *s.MinItems:
+		return fmt.Sprintf("must have exactly %d items", *s.MinItems)
+	case s.MinItems != nil && n < *s.MinItems:
+		return fmt.Sprintf("must have at least %d items", *s.MinItems)
+	case s.MaxItems != nil && n > *s.MaxItems:
+		return fmt.Sprintf("must have at most %d items", *s.MaxItems)
+	}
+	return ""
+}
+
+// schemaPath appends key to a value path.
+func schemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// hasType reports whether a decoded JSON value has the JSON Schema type t.
+func hasType(value interface{}, t string) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case string:
+		return t == "string"
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	}
+	return false
+}
+
+// typeName phrases a JSON Schema type for error messages.
+func typeName(t string) string {
+	switch t {
+	case "integer":
+		return "a whole number"
+	case "number":
+		return "a number"
+	case "boolean":
+		return "true or false"
+	case "array":
+		return "a list"
+	case "object":
+		return "an object"
+	}
+	return "a " + t
+}
+
+// describe formats a decoded JSON value for error messages.
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return formatNumber(v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// formatNumber formats n without a trailing ".0" or exponent for whole numbers.
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+// choices lists enum values for error messages: `one of "a", "b" or "c"`.
+func choices(enum []interface{}) string {
+	names := make([]string, len(enum))
+	for i, v := range enum {
+		names[i] = describe(v)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "one of " + strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// inEnum reports whether value equals one of the enum values.
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, v := range enum {
+		if jsonEqual(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual compares two decoded JSON values.
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,441 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestWeightedConfigurationDirected is a regression test: adding strength to an
+// edge whose endpoints came out of the matching in descending order used to
+// look the edge up under the reversed key, which doesn't exist in a directed
+// graph, and panic.
+func TestWeightedConfigurationDirected(t *testing.T) {
+	degrees := []int{3, 3, 2, 2, 2, 2, 1, 1}
+	strengths := []int{6, 5, 4, 4, 3, 3, 2, 1}
+	for seed := int64(1); seed <= 50; seed++ {
+		G, _ := WeightedConfigurationSimulation(degrees, strengths, &SimOptions{}, rand.New(rand.NewSource(seed)))
+		if G == nil || !G.Directed {
+			t.Fatalf("seed %d: expected a directed graph, got %+v", seed, G)
+		}
+		strength := make([]int, len(degrees))
+		for key, edge := range G.Edges {
+			if key.Source != edge.Source || key.Target != edge.Target || edge.Source > edge.Target {
+				t.Fatalf("seed %d: edge %d->%d stored under key %v", seed, edge.Source, edge.Target, key)
+			}
+			strength[edge.Source] += edge.Weight
+			strength[edge.Target] += edge.Weight
+		}
+		for i, s := range strength {
+			if s > strengths[i] {
+				t.Errorf("seed %d: node %d has strength %d, more than its target %d", seed, i, s, strengths[i])
+			}
+		}
+	}
+}
+
+// selectionDraws is how many choices the selection tests make.
+const selectionDraws = 200000
+
+// checkShares fails the test unless each index was chosen in proportion to
+// its weight, within four standard deviations of the binomial count.
+func checkShares(t *testing.T, counts []int, weights []float64) {
+	t.Helper()
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	for i, w := range weights {
+		share := w / total
+		got := float64(counts[i]) / selectionDraws
+		tolerance := 4 * math.Sqrt(share*(1-share)/selectionDraws)
+		if w == 0 && counts[i] != 0 {
+			t.Errorf("index %d has weight 0 but was chosen %d times", i, counts[i])
+		} else if math.Abs(got-share) > tolerance {
+			t.Errorf("index %d chosen with frequency %.4f, want %.4f ± %.4f", i, got, share, tolerance)
+		}
+	}
+}
+
+// TestWeightedChoiceDegreeShares checks preferential attachment's target
+// selection: each node is chosen in proportion to its degree, so node 0 isn't
+// favored and nodes of degree 0 (at either end) are never chosen.
+func TestWeightedChoiceDegreeShares(t *testing.T) {
+	degrees := []int{0, 1, 1, 5, 2, 0, 3, 0}
+	weights := make([]float64, len(degrees))
+	for i, d := range degrees {
+		weights[i] = float64(d)
+	}
+	rng := rand.New(rand.NewSource(275))
+	counts := make([]int, len(degrees))
+	for n := 0; n < selectionDraws; n++ {
+		counts[weightedChoice(degrees, rng)]++
+	}
+	checkShares(t, counts, weights)
+
+	// With equal degrees the first node must not be over-selected.
+	equal := []int{1, 1, 1, 1}
+	counts = make([]int, len(equal))
+	for n := 0; n < selectionDraws; n++ {
+		counts[weightedChoice(equal, rng)]++
+	}
+	checkShares(t, counts, []float64{1, 1, 1, 1})
+}
+
+// TestWeightedChoiceProportions checks that weightedChoice and
+// weightedChoiceFloat pick each index in proportion to its weight, and
+// uniformly when every weight is zero.
+func TestWeightedChoiceProportions(t *testing.T) {
+	rng := rand.New(rand.NewSource(204))
+
+	ints := []int{10, 0, 30, 60}
+	counts := make([]int, len(ints))
+	for n := 0; n < selectionDraws; n++ {
+		counts[weightedChoice(ints, rng)]++
+	}
+	checkShares(t, counts, []float64{10, 0, 30, 60})
+
+	floats := []float64{0.25, 1.5, 0, 0.05, 3.2}
+	counts = make([]int, len(floats))
+	for n := 0; n < selectionDraws; n++ {
+		counts[weightedChoiceFloat(floats, rng)]++
+	}
+	checkShares(t, counts, floats)
+
+	counts = make([]int, 3)
+	for n := 0; n < selectionDraws; n++ {
+		counts[weightedChoice([]int{0, 0, 0}, rng)]++
+	}
+	checkShares(t, counts, []float64{1, 1, 1})
+	counts = make([]int, 3)
+	for n := 0; n < selectionDraws; n++ {
+		counts[weightedChoiceFloat([]float64{0, 0, 0}, rng)]++
+	}
+	checkShares(t, counts, []float64{1, 1, 1})
+}
+
+// TestPreferentialAttachmentSkew checks that preferential attachment grows
+// hubs: the best-connected node ends up with many times the mean degree, which
+// uniform attachment of the same size wouldn't produce.
+func TestPreferentialAttachmentSkew(t *testing.T) {
+	const n, m = 2000, 2
+	G, err := PreferentialAttachmentSimulation(n, 1, m, "complete_seed", 1, nil, false, &SimOptions{}, rand.New(rand.NewSource(252)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxDegree, total := 0, 0
+	for _, d := range G.degrees() {
+		total += d
+		if d > maxDegree {
+			maxDegree = d
+		}
+	}
+	mean := float64(total) / n
+	if float64(maxDegree) < 5*mean {
+		t.Errorf("max degree %d is less than 5 times the mean degree %.2f", maxDegree, mean)
+	}
+}
+
+// TestUndirectedNoReciprocalEdges checks that undirected generation stores
+// each link once, lower id first, rather than as both i->j and j->i.
+func TestUndirectedNoReciprocalEdges(t *testing.T) {
+	opts := &SimOptions{Undirected: true}
+	random, err := RandomSimulation(200, 10, 0.05, true, opts, rand.New(rand.NewSource(253)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	homophily, err := HomophilySimulation(200, 10, 3, nil, nil, 0.1, 0.02, true, opts, rand.New(rand.NewSource(253)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, G := range map[string]*Graph{"random": random, "homophily": homophily} {
+		if G.Directed {
+			t.Fatalf("%s: graph is directed", name)
+		}
+		if len(G.Edges) == 0 {
+			t.Fatalf("%s: no edges generated", name)
+		}
+		for key, edge := range G.Edges {
+			if edge.Source >= edge.Target || key != (EdgeKey{edge.Source, edge.Target}) {
+				t.Errorf("%s: edge %d-%d stored under key %v", name, edge.Source, edge.Target, key)
+			}
+			if _, ok := G.Edges[EdgeKey{edge.Target, edge.Source}]; ok {
+				t.Errorf("%s: edge %d-%d stored in both directions", name, edge.Source, edge.Target)
+			}
+		}
+	}
+}
+
+// edgeShapes counts the self-loops and the edges of weight above 1 (repeated
+// links) in G.
+func edgeShapes(G *Graph) (selfLoops, repeated int) {
+	for _, edge := range G.Edges {
+		if edge.Source == edge.Target {
+			selfLoops++
+		}
+		if edge.Weight > 1 {
+			repeated++
+		}
+	}
+	return selfLoops, repeated
+}
+
+// TestSelfLoopsAndMultiEdges checks every combination of AllowSelfLoops and
+// AllowMultiEdges: preferential attachment only makes self-loops and repeated
+// links when the matching option is set, and the random strategy only links a
+// node to itself with AllowSelfLoops.
+func TestSelfLoopsAndMultiEdges(t *testing.T) {
+	for _, selfLoops := range []bool{false, true} {
+		for _, multiEdges := range []bool{false, true} {
+			opts := &SimOptions{AllowSelfLoops: selfLoops, AllowMultiEdges: multiEdges}
+			name := fmt.Sprintf("self_loops=%v,multi_edges=%v", selfLoops, multiEdges)
+			loops, repeated := 0, 0
+			for seed := int64(1); seed <= 20; seed++ {
+				G, err := PreferentialAttachmentSimulation(30, 1, 6, "complete_seed", 1, nil, true, opts, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				l, r := edgeShapes(G)
+				loops += l
+				repeated += r
+			}
+			if (loops > 0) != selfLoops {
+				t.Errorf("%s: preferential attachment made %d self-loops", name, loops)
+			}
+			if (repeated > 0) != multiEdges {
+				t.Errorf("%s: preferential attachment made %d repeated links", name, repeated)
+			}
+
+			G, err := RandomSimulation(30, 5, 0.1, true, opts, rand.New(rand.NewSource(287)))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if loops, _ := edgeShapes(G); (loops > 0) != selfLoops {
+				t.Errorf("%s: random made %d self-loops", name, loops)
+			}
+		}
+	}
+}
+
+// TestConvergenceCheckReports checks that ConvergenceCheck stops once the
+// metric has held steady for 'patience' steps and reports that step to
+// ConvergedFunc instead of printing it.
+func TestConvergenceCheckReports(t *testing.T) {
+	convergedAt := 0
+	opts := &SimOptions{ConvergedFunc: func(step int, value float64) {
+		convergedAt = step
+		if value != 3 {
+			t.Errorf("converged with edge count %v, want 3", value)
+		}
+	}}
+	stop := ConvergenceCheck("edge_count", 0.01, 2, opts)
+	g := testGraph(4, true, [2]int{0, 1})
+	steps := [][2]int{{1, 2}, {2, 3}, {2, 3}, {2, 3}, {2, 3}}
+	for step, pair := range steps {
+		g.AddEdge(pair[0], pair[1], 1)
+		if stop(step+1, g) {
+			break
+		}
+	}
+	if convergedAt != 4 {
+		t.Errorf("ConvergedFunc called at step %d, want 4", convergedAt)
+	}
+}
+
+// TestRunPipelineReportsStages checks that RunPipeline reports each stage and
+// the homophily_rewire result through SimOptions rather than printing them.
+func TestRunPipelineReportsStages(t *testing.T) {
+	config, err := loadConfigString(t, `{"num_agents": 60, "pipeline": [
+		{"strategy": "preferential_attachment", "edges_per_step": 2},
+		{"strategy": "homophily_rewire", "homophily_groups": 3, "rewire_fraction": 0.5}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stages []string
+	rewiredStage, rewired := 0, -1
+	opts := NewSimOptions(config)
+	opts.StageFunc = func(stage int, strategy string) {
+		stages = append(stages, fmt.Sprintf("%d:%s", stage, strategy))
+	}
+	opts.RewireFunc = func(stage, n int) {
+		rewiredStage, rewired = stage, n
+	}
+	if _, err := RunPipeline(config, opts, rand.New(rand.NewSource(206))); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(stages, " "), "1:preferential_attachment 2:homophily_rewire"; got != want {
+		t.Errorf("stages reported as %q, want %q", got, want)
+	}
+	if rewiredStage != 2 || rewired <= 0 {
+		t.Errorf("RewireFunc got stage %d, %d edges rewired; want stage 2 and some edges", rewiredStage, rewired)
+	}
+}
+
+// TestGNPChecksMemory checks that directed G(n, p) applies the memory budget
+// even though its random skips rarely land on an exact multiple of the check
+// interval.
+func TestGNPChecksMemory(t *testing.T) {
+	opts := &SimOptions{MaxMemoryMB: 1}
+	if _, err := GNPSimulation(3000, 0.01, false, opts, rand.New(rand.NewSource(313))); err == nil {
+		t.Error("expected the 1 MB budget to abort the run")
+	}
+}
+
+// TestSimulateNilOptions checks that Simulate accepts nil options, including
+// when weight_distribution makes it add a WeightFunc to them.
+func TestSimulateNilOptions(t *testing.T) {
+	config, err := loadConfigString(t, `{"linking_strategy": "random", "num_agents": 20, "time_steps": 3, "p": 0.2, "weight_distribution": "exponential"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	G, err := Simulate(config, nil, rand.New(rand.NewSource(277)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, edge := range G.Edges {
+		if edge.FloatWeight <= 0 {
+			t.Fatalf("edge %d->%d has no continuous weight", edge.Source, edge.Target)
+		}
+	}
+}
+
+// TestRunPipelineNilOptions checks that RunPipeline accepts nil options.
+func TestRunPipelineNilOptions(t *testing.T) {
+	config, err := loadConfigString(t, `{"num_agents": 30, "pipeline": [
+		{"strategy": "random", "p": 0.05},
+		{"strategy": "homophily_rewire", "homophily_groups": 2, "rewire_fraction": 0.5}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunPipeline(config, nil, rand.New(rand.NewSource(279))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGNPTinyP checks that a p so small that 1-p rounds to 1 gives an empty
+// graph instead of infinite skips.
+func TestGNPTinyP(t *testing.T) {
+	for _, undirected := range []bool{false, true} {
+		G, err := GNPSimulation(100, 1e-18, false, &SimOptions{Undirected: undirected}, rand.New(rand.NewSource(313)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(G.Edges) != 0 {
+			t.Errorf("undirected=%v: %d edges, want none", undirected, len(G.Edges))
+		}
+	}
+}
+
+// TestMergeGraphsNodeMismatch checks that pipeline stages with different node
+// counts are reported instead of indexing past the smaller graph.
+func TestMergeGraphsNodeMismatch(t *testing.T) {
+	small, _ := CompleteSimulation(4, false, &SimOptions{})
+	large, _ := CompleteSimulation(6, false, &SimOptions{})
+	if err := mergeGraphs(small, large, false); err == nil {
+		t.Fatal("merging a 6-node graph into a 4-node graph: expected an error")
+	}
+	if len(small.Edges) != 12 {
+		t.Errorf("failed merge changed the graph: %d edges, want 12", len(small.Edges))
+	}
+	other, _ := RingLatticeSimulation(4, 2, false, &SimOptions{})
+	if err := mergeGraphs(small, other, false); err != nil {
+		t.Errorf("merging equal-sized graphs: %v", err)
+	}
+}
+
+// denseEdgeCount is the number of nodes in the complete graph the edge map
+// benchmarks fill.
+const denseEdgeCount = 300
+
+// BenchmarkEdgeMapStringKey measures the edge map as it was keyed before
+// EdgeKey: a formatted "i_j" string per lookup and insert.
+func BenchmarkEdgeMapStringKey(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		edges := make(map[string]*Edge)
+		for i := 0; i < denseEdgeCount; i++ {
+			for j := 0; j < denseEdgeCount; j++ {
+				if i == j {
+					continue
+				}
+				key := fmt.Sprintf("%d_%d", i, j)
+				if edge, ok := edges[key]; ok {
+					edge.Weight++
+					continue
+				}
+				edges[key] = &Edge{Source: i, Target: j, Weight: 1}
+			}
+		}
+	}
+}
+
+// BenchmarkEdgeMapStructKey is BenchmarkEdgeMapStringKey with EdgeKey keys.
+func BenchmarkEdgeMapStructKey(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		edges := make(map[EdgeKey]*Edge)
+		for i := 0; i < denseEdgeCount; i++ {
+			for j := 0; j < denseEdgeCount; j++ {
+				if i == j {
+					continue
+				}
+				key := EdgeKey{i, j}
+				if edge, ok := edges[key]; ok {
+					edge.Weight++
+					continue
+				}
+				edges[key] = &Edge{Source: i, Target: j, Weight: 1}
+			}
+		}
+	}
+}
+
+// BenchmarkCompleteSimulation builds a dense graph through addEdge, the path
+// every strategy takes.
+func BenchmarkCompleteSimulation(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := CompleteSimulation(denseEdgeCount, true, &SimOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkSizes are the node counts the generator benchmarks run at.
+var benchmarkSizes = []int{100, 1000, 10000}
+
+// benchmarkGenerator runs generate once per iteration at each benchmark size,
+// with a fixed seed so every run builds the same networks.
+func benchmarkGenerator(b *testing.B, generate func(n int, rng *rand.Rand) (*Graph, error)) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < b.N; i++ {
+				if _, err := generate(n, rng); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRandom(b *testing.B) {
+	benchmarkGenerator(b, func(n int, rng *rand.Rand) (*Graph, error) {
+		return RandomSimulation(n, 10, 0.05, true, &SimOptions{}, rng)
+	})
+}
+
+func BenchmarkPreferentialAttachment(b *testing.B) {
+	benchmarkGenerator(b, func(n int, rng *rand.Rand) (*Graph, error) {
+		return PreferentialAttachmentSimulation(n, 10, 2, "complete_seed", 1, nil, true, &SimOptions{}, rng)
+	})
+}
+
+func BenchmarkHomophily(b *testing.B) {
+	benchmarkGenerator(b, func(n int, rng *rand.Rand) (*Graph, error) {
+		return HomophilySimulation(n, 10, 3, nil, nil, 0.1, 0.01, true, &SimOptions{}, rng)
+	})
+}
@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// VerifyCheck is the outcome of one consistency check run by the verify subcommand.
+type VerifyCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// VerifyNetwork checks whether G plausibly came from config: matching node
+// count, an edge count within what the strategy can produce, no self-loops,
+// weights consistent with edge_weights, and groups consistent with homophily.
+func VerifyNetwork(config *Config, G *Graph) []VerifyCheck {
+	var checks []VerifyCheck
+	add := func(name string, ok bool, detail string, args ...interface{}) {
+		checks = append(checks, VerifyCheck{Name: name, OK: ok, Detail: fmt.Sprintf(detail, args...)})
+	}
+
+	add("node count", G.NumAgents == config.NumAgents, "network has %d nodes, config has num_agents %d", G.NumAgents, config.NumAgents)
+	add("direction", G.Directed == config.Directed, "network has directed=%t, config has directed=%t", G.Directed, config.Directed)
+
+	n, m := config.NumAgents, config.EdgesPerStep
+	maxEdges := n * (n - 1)
+	if config.AllowSelfLoops {
+		maxEdges = n * n
+	}
+	if !config.Directed {
+		maxEdges = n * (n - 1) / 2
+		if config.AllowSelfLoops {
+			maxEdges = n * (n + 1) / 2
+		}
+	}
+	switch {
+	case len(config.Pipeline) > 0:
+		// Stages can merge several strategies; only the simple-graph bound applies.
+	case config.LinkingStrategy == "preferential_attachment" || config.LinkingStrategy == "fitness":
+		// Each node after the initial m+1 adds at most m edges.
+		maxEdges = (n - m - 1) * m
+		if config.ColdStart == "complete_seed" {
+			maxEdges += m * (m + 1) / 2
+		}
+	case config.LinkingStrategy == "gnm":
+		maxEdges = config.NumEdges
+	case config.LinkingStrategy == "small_world" || config.LinkingStrategy == "ring_lattice":
+		maxEdges = n * (config.K / 2)
+	case config.LinkingStrategy == "bipartite" && len(config.Partition) == 2:
+		maxEdges = config.Partition[0] * config.Partition[1]
+	case config.LinkingStrategy == "grid":
+		maxEdges = gridEdges(config.Rows, config.Cols, config.GridDiagonals, config.GridTorus)
+	case config.LinkingStrategy == "complete" || config.LinkingStrategy == "gnp":
+		// At most every pair is linked once: the simple-graph bound.
+	case config.LinkingStrategy == "geometric":
+		// Each pair within the radius is linked once.
+		maxEdges = n * (n - 1) / 2
+	case config.LinkingStrategy == "weighted_configuration" || config.LinkingStrategy == "configuration":
+		sum := 0
+		for _, d := range config.DegreeSequence {
+			sum += d
+		}
+		maxEdges = sum / 2
+	default:
+		// Random and homophily add at most one edge per node per time step.
+		if steps := n * config.TimeSteps; steps < maxEdges {
+			maxEdges = steps
+		}
+	}
+	add("edge count", len(G.Edges) <= maxEdges, "%d edges, at most %d expected for this config", len(G.Edges), maxEdges)
+
+	selfLoops, badWeights := 0, 0
+	for _, edge := range G.Edges {
+		if edge.Source == edge.Target {
+			selfLoops++
+		}
+		if (config.EdgeWeights && edge.Weight < 1) || (!config.EdgeWeights && edge.Weight != 0) {
+			badWeights++
+		}
+	}
+	if config.AllowSelfLoops {
+		add("self-loops", true, "%d self-loops found (allow_self_loops is on)", selfLoops)
+	} else {
+		add("self-loops", selfLoops == 0, "%d self-loops found", selfLoops)
+	}
+	add("edge weights", badWeights == 0, "%d edges with weights inconsistent with edge_weights=%t", badWeights, config.EdgeWeights)
+
+	if config.LinkingStrategy == "homophily" && len(config.Pipeline) == 0 {
+		badGroups := 0
+		// Without group_probs the assignment is deterministic.
+		var expected map[int]int
+		if len(config.GroupProbs) == 0 {
+			expected = assignGroups(config.NumAgents, config.HomophilyGroups, nil, config.GroupSizes, nil)
+		}
+		for i := 0; i < G.NumAgents; i++ {
+			group, ok := G.Groups[i]
+			if !ok || group < 0 || group >= config.HomophilyGroups {
+				badGroups++
+			} else if expected != nil && group != expected[i] {
+				badGroups++
+			}
+		}
+		add("groups", badGroups == 0, "%d nodes with missing or unexpected group (homophily_groups=%d)", badGroups, config.HomophilyGroups)
+	} else if config.LinkingStrategy == "bipartite" && len(config.Pipeline) == 0 {
+		expected := assignGroups(config.NumAgents, 2, nil, config.Partition, nil)
+		badGroups, within := 0, 0
+		for i := 0; i < G.NumAgents; i++ {
+			if group, ok := G.Groups[i]; !ok || group != expected[i] {
+				badGroups++
+			}
+		}
+		for _, edge := range G.Edges {
+			if expected[edge.Source] == expected[edge.Target] {
+				within++
+			}
+		}
+		add("groups", badGroups == 0, "%d nodes outside their partition set (partition=%v)", badGroups, config.Partition)
+		add("bipartite", within == 0, "%d edges join two nodes of the same set", within)
+	} else if len(config.Pipeline) == 0 {
+		add("groups", len(G.Groups) == 0, "%d group assignments for a %s network", len(G.Groups), config.LinkingStrategy)
+	}
+	return checks
+}